@@ -0,0 +1,553 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/Nastradamus/useless-operator/pkg/history"
+	"github.com/Nastradamus/useless-operator/pkg/notify"
+	"github.com/Nastradamus/useless-operator/pkg/policy"
+	prom "github.com/Nastradamus/useless-operator/pkg/prometheus"
+	ukube "github.com/Nastradamus/useless-operator/pkg/ukubernetes"
+	"k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog"
+)
+
+// Exit codes of all commands
+const (
+	exitOK = 0
+	// Findings exceed -max-findings, or the new report of diff has new findings
+	exitFindings = 1
+	// Invalid flags or arguments
+	exitUsage = 2
+	// Scan or action failed
+	exitError = 3
+)
+
+// Prefix of environment variables overriding flags, e.g. USELESS_OPERATOR_PROM_URI for -prom-uri
+const envPrefix = "USELESS_OPERATOR_"
+
+// Version of the build, set by -ldflags "-X main.version=..."
+var version = "dev"
+
+// options are flags of all commands, commands register only the groups they use
+type options struct {
+	config            string
+	profile           bool
+	runOutsideCluster bool
+	kubeconfig        string
+	context           string
+
+	// Detection
+	promAddr            string
+	clusters            string
+	promConfig          prom.ClientConfig
+	promHeaders         string
+	period              int
+	ingressClasses      string
+	ingressControllers  string
+	ingressProvider     string
+	meshProvider        string
+	jobAge              int
+	cronJobSuspendedAge int
+	cronJobFailedRuns   int
+	hpaUtilization      int
+	namespaceAge        int
+	ownerKeys           string
+	output              string
+	maxFindings         int
+
+	// Delivery of findings
+	recordEvents    bool
+	writeReports    bool
+	webhookURL      string
+	webhookTeamURLs string
+	webhookFormat   string
+	webhookRate     float64
+	webhookState    string
+	historySpec     string
+
+	// Actions on idle workloads, policies can't enable them without act
+	act            bool
+	markWorkloads  bool
+	lifecycle      bool
+	warnAfter      int
+	scaleDownAfter int
+	deleteAfter    int
+
+	// Operator mode
+	interval             int
+	listenAddr           string
+	leaderElect          bool
+	leaderElectNamespace string
+	leaderElectName      string
+}
+
+// command is a subcommand of the CLI
+type command struct {
+	name    string
+	args    string
+	summary string
+	run     func(args []string) int
+}
+
+// commands returns subcommands in the order of the usage
+func commands() []*command {
+	return []*command{
+		{"scan", "", "Find useless objects and print the report, without changing the cluster", scanCommand},
+		{"report", "", "Scan and deliver findings: Events, UselessReports, webhooks and scan history",
+			reportCommand},
+		{"remediate", "", "Scan, deliver findings and mark idle workloads or move them through the lifecycle",
+			remediateCommand},
+		{"restore", "<namespace>/<kind>/<name>...", "Scale up workloads scaled down by the lifecycle and " +
+			"remove their marks", restoreCommand},
+		{"serve", "", "Run as an operator: scan periodically, watch policies, serve health and metrics, " +
+			"elect a leader among replicas", serveCommand},
+		{"diff", "<old report> <new report>", "Compare two reports saved by -output", diffCommand},
+		{"version", "", "Print the version", versionCommand},
+	}
+}
+
+// usage prints commands
+func usage() {
+	_, _ = fmt.Fprintf(os.Stderr, "Usage: %s <command> [flags] [arguments]\n\nCommands:\n", os.Args[0])
+	for _, cmd := range commands() {
+		_, _ = fmt.Fprintf(os.Stderr, "  %-10v %v\n", cmd.name, cmd.summary)
+	}
+	_, _ = fmt.Fprintf(os.Stderr, "\nRun '%s <command> -h' for flags of the command. Flags can be set by "+
+		"%s<FLAG> environment variables (e.g. %sPROM_URI) and in the -config file, command line wins.\n",
+		os.Args[0], envPrefix, envPrefix)
+}
+
+// newFlagSet returns flags of the command, groups register flags into options
+func newFlagSet(cmd string, o *options, groups ...func(*flag.FlagSet, *options)) *flag.FlagSet {
+	fs := flag.NewFlagSet(cmd, flag.ContinueOnError)
+	for _, group := range groups {
+		group(fs, o)
+	}
+	fs.Usage = func() {
+		for _, c := range commands() {
+			if c.name == cmd {
+				_, _ = fmt.Fprintf(fs.Output(), "Usage: %s %s [flags] %s\n%s\n\nFlags:\n", os.Args[0], cmd,
+					c.args, c.summary)
+			}
+		}
+		fs.PrintDefaults()
+	}
+
+	return fs
+}
+
+// commonFlags are flags of all commands connecting to the cluster
+func commonFlags(fs *flag.FlagSet, o *options) {
+	// klog flags (-v, -logtostderr, ...) are parsed with flags of the command
+	klog.InitFlags(fs)
+	verbosity := fs.Lookup("v")
+	verbosity.DefValue = "1"
+	_ = verbosity.Value.Set("1")
+	klog.SetOutput(os.Stdout)
+
+	fs.StringVar(&o.config, "config", "", "YAML or JSON file with flag values by flag name, e.g. "+
+		"\"prom-uri: http://prometheus:9090\".")
+	fs.BoolVar(&o.profile, "profile", false, "Enable profiling on http://0.0.0.0:6060")
+	fs.BoolVar(&o.runOutsideCluster, "run-outside-cluster", false, "Set this flag when running "+
+		"outside of the cluster.")
+	fs.StringVar(&o.kubeconfig, "kubeconfig", "", "Path to the kubeconfig, $KUBECONFIG or $HOME/.kube/config "+
+		"by default. It and $KUBECONFIG imply -run-outside-cluster.")
+	fs.StringVar(&o.context, "context", "", "Kubeconfig context, the current one by default. Implies "+
+		"-run-outside-cluster.")
+}
+
+// detectionFlags configure detectors
+func detectionFlags(fs *flag.FlagSet, o *options) {
+	fs.StringVar(&o.promAddr, "prom-uri", "", "Prometheus URI (e.g. http://localhost:9091).")
+	fs.StringVar(&o.clusters, "clusters", "", "Comma-separated list of kubeconfig contexts scanned into one "+
+		"report, each as context=prometheus-uri or context (scanned with -prom-uri).")
+	fs.StringVar(&o.promConfig.BearerToken, "prom-bearer-token", "", "Bearer token of Prometheus requests, "+
+		"better set by "+envPrefix+"PROM_BEARER_TOKEN.")
+	fs.StringVar(&o.promConfig.BearerTokenFile, "prom-bearer-token-file", "", "File with the bearer token of "+
+		"Prometheus requests, read on every request (e.g. a service account token).")
+	fs.StringVar(&o.promConfig.Username, "prom-username", "", "Basic auth username of Prometheus requests.")
+	fs.StringVar(&o.promConfig.Password, "prom-password", "", "Basic auth password of Prometheus requests, "+
+		"better set by "+envPrefix+"PROM_PASSWORD.")
+	fs.StringVar(&o.promConfig.PasswordFile, "prom-password-file", "", "File with the basic auth password of "+
+		"Prometheus requests.")
+	fs.StringVar(&o.promConfig.CAFile, "prom-ca-file", "", "CA bundle verifying Prometheus, system roots by "+
+		"default.")
+	fs.StringVar(&o.promConfig.CertFile, "prom-cert-file", "", "Client certificate of Prometheus requests.")
+	fs.StringVar(&o.promConfig.KeyFile, "prom-key-file", "", "Key of the client certificate.")
+	fs.BoolVar(&o.promConfig.InsecureSkipVerify, "prom-insecure-skip-verify", false, "Don't verify the "+
+		"certificate of Prometheus.")
+	fs.StringVar(&o.promHeaders, "prom-headers", "", "Comma-separated list of name=value headers of "+
+		"Prometheus requests, e.g. X-Scope-OrgID=team-a for Cortex or Mimir tenants.")
+	fs.StringVar(&o.promConfig.ProxyURL, "prom-proxy-url", "", "Proxy of Prometheus requests, $HTTPS_PROXY, "+
+		"$HTTP_PROXY and $NO_PROXY by default.")
+	fs.DurationVar(&o.promConfig.Timeout, "prom-timeout", prom.DefaultTimeout, "Timeout of a Prometheus query.")
+	fs.IntVar(&o.period, "period", 6, "Observation period in hours.")
+	fs.StringVar(&o.ingressClasses, "ingress-class", "", "Comma-separated list of ingress classes "+
+		"to analyze (default: all).")
+	fs.StringVar(&o.ingressControllers, "ingress-controller", "", "Comma-separated list of ingress "+
+		"controllers to analyze, e.g. k8s.io/ingress-nginx (resolved via IngressClass).")
+	fs.StringVar(&o.ingressProvider, "ingress-provider", "auto", "Comma-separated list of ingress "+
+		"controllers' metrics to use: nginx, traefik, haproxy, contour, envoy-gateway, istio or auto "+
+		"(detect from Prometheus). Entries reported by several controllers (e.g. Services) are idle only if "+
+		"idle for all of them.")
+	fs.StringVar(&o.meshProvider, "mesh-provider", "auto", "Comma-separated list of service meshes' "+
+		"request metrics to use: istio, linkerd, auto (detect from Prometheus) or none.")
+	fs.IntVar(&o.jobAge, "job-age", 7, "Report Jobs finished more than this many days ago.")
+	fs.IntVar(&o.cronJobSuspendedAge, "cronjob-suspended-age", 30, "Report CronJobs suspended and "+
+		"not scheduled for more than this many days.")
+	fs.IntVar(&o.cronJobFailedRuns, "cronjob-failed-runs", 3, "Report CronJobs whose last runs all failed "+
+		"(0 disables). Runs are counted from failed Jobs they keep (failedJobsHistoryLimit, 1 by default), "+
+		"CronJobs keeping less are reported if they never succeeded for -job-age (batch/v1 only).")
+	fs.IntVar(&o.hpaUtilization, "hpa-utilization", 5, "Report HPAs pinned at minReplicas whose CPU "+
+		"utilization (percent of requests) stayed below this value.")
+	fs.IntVar(&o.namespaceAge, "namespace-age", 90, "Report namespaces without modifications for this "+
+		"many days (0 disables).")
+	fs.StringVar(&o.ownerKeys, "owner-keys", "owner,team",
+		"Comma-separated annotation and label keys holding owners, in order of preference. Objects "+
+			"without them are attributed to owners of their namespaces. Deployment tools (Helm, kustomize, "+
+			"...) aren't owners.")
+}
+
+// oneShotFlags are flags of commands scanning once
+func oneShotFlags(fs *flag.FlagSet, o *options) {
+	fs.StringVar(&o.output, "output", "", "Save the report as JSON to this file, e.g. to compare reports "+
+		"with the diff command.")
+	fs.IntVar(&o.maxFindings, "max-findings", -1, "Exit with code 1 if there are more findings "+
+		"(negative disables).")
+}
+
+// deliveryFlags configure where findings are delivered besides the report
+func deliveryFlags(fs *flag.FlagSet, o *options) {
+	fs.BoolVar(&o.recordEvents, "events", false, "Record Kubernetes Events on flagged Deployments, "+
+		"StatefulSets and Ingresses.")
+	fs.BoolVar(&o.writeReports, "reports", false, "Write findings into a UselessReport per namespace and "+
+		"the cluster-wide ClusterUselessReport.")
+	fs.StringVar(&o.webhookURL, "webhook-url", "", "Webhook receiving summaries of teams without their "+
+		"own webhook (empty disables).")
+	fs.StringVar(&o.webhookTeamURLs, "webhook-team-urls", "", "Comma-separated list of team=url webhooks "+
+		"of teams.")
+	fs.StringVar(&o.webhookFormat, "webhook-format", "json", "Webhook payload format: json, slack or teams.")
+	fs.Float64Var(&o.webhookRate, "webhook-rate", 1, "Maximum webhook requests per second.")
+	fs.StringVar(&o.webhookState, "webhook-state", "", "File keeping findings already sent to webhooks, "+
+		"so only new findings are sent (empty sends all findings every run).")
+	fs.StringVar(&o.historySpec, "history", "file:useless-operator-history.json", "Store of scan history "+
+		"tracking findings across scans: file:<path>, configmap:<namespace>/<name> or empty to disable.")
+}
+
+// actionFlags configure actions on idle workloads
+func actionFlags(fs *flag.FlagSet, o *options) {
+	o.act = true
+	fs.BoolVar(&o.markWorkloads, "mark", false, "Stamp idle workloads with useless-operator/* annotations "+
+		"and state label, remove them once traffic resumes.")
+	fs.BoolVar(&o.lifecycle, "lifecycle", false, "Warn idle workloads, scale them down and optionally "+
+		"delete them after grace periods (implies -mark).")
+	fs.IntVar(&o.warnAfter, "warn-after", 24, "Warn workloads idle for this many hours.")
+	fs.IntVar(&o.scaleDownAfter, "scale-down-after", 72, "Scale down workloads warned this many hours ago.")
+	fs.IntVar(&o.deleteAfter, "delete-after", 0, "Delete workloads scaled down this many hours ago "+
+		"(0 disables).")
+}
+
+// serveFlags configure the operator mode
+func serveFlags(fs *flag.FlagSet, o *options) {
+	fs.IntVar(&o.interval, "interval", 60, "Scan every this many minutes.")
+	fs.StringVar(&o.listenAddr, "listen-address", ":8080", "Address serving /healthz and /metrics.")
+	fs.BoolVar(&o.leaderElect, "leader-elect", false, "Elect a leader among replicas via a Lease, only "+
+		"the leader scans and acts.")
+	fs.StringVar(&o.leaderElectNamespace, "leader-elect-namespace", "", "Namespace of the Lease "+
+		"(default: $POD_NAMESPACE or default).")
+	fs.StringVar(&o.leaderElectName, "leader-elect-name", "useless-operator", "Name of the Lease.")
+}
+
+// parseFlags parses command line, then sets flags missing there from environment variables and the config file.
+// Errors are printed like errors of the command line.
+func parseFlags(fs *flag.FlagSet, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := applyOverrides(fs); err != nil {
+		_, _ = fmt.Fprintln(fs.Output(), err)
+		return err
+	}
+
+	return nil
+}
+
+// applyOverrides sets flags missing on command line from environment variables, then from the config file
+func applyOverrides(fs *flag.FlagSet) error {
+	set := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+	})
+
+	var err error
+	fs.VisitAll(func(f *flag.Flag) {
+		value, ok := os.LookupEnv(envName(f.Name))
+		if !ok || set[f.Name] || err != nil {
+			return
+		}
+		if err = fs.Set(f.Name, value); err != nil {
+			err = fmt.Errorf("invalid %v: %v", envName(f.Name), err)
+		}
+		set[f.Name] = true
+	})
+	if err != nil {
+		return err
+	}
+
+	config := fs.Lookup("config")
+	if config == nil || config.Value.String() == "" {
+		return nil
+	}
+	values, err := loadConfig(config.Value.String())
+	if err != nil {
+		return err
+	}
+	for name, value := range values {
+		if fs.Lookup(name) == nil {
+			return fmt.Errorf("unknown flag %q in config file %v", name, config.Value.String())
+		}
+		if set[name] {
+			continue
+		}
+		if err := fs.Set(name, fmt.Sprint(value)); err != nil {
+			return fmt.Errorf("invalid %q in config file %v: %v", name, config.Value.String(), err)
+		}
+	}
+
+	return nil
+}
+
+// envName returns environment variable overriding the flag
+func envName(flagName string) string {
+	return envPrefix + strings.ToUpper(strings.Replace(flagName, "-", "_", -1))
+}
+
+// loadConfig reads flag values by flag name from YAML or JSON file
+func loadConfig(path string) (map[string]interface{}, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	values := map[string]interface{}{}
+	if err := yaml.NewYAMLOrJSONDecoder(f, 4096).Decode(&values); err != nil {
+		return nil, fmt.Errorf("can't parse config file %v: %v", path, err)
+	}
+
+	return values, nil
+}
+
+// validate checks flags of scanning commands, so mistakes are reported before scanning
+func (o *options) validate() error {
+	specs, err := o.clusterSpecs()
+	if err != nil {
+		return err
+	}
+	for _, spec := range specs {
+		if _, err := url.ParseRequestURI(spec.promAddr); err != nil {
+			if spec.name != "" {
+				return fmt.Errorf("invalid Prometheus URI of cluster %v: %v", spec.name, err)
+			}
+			return fmt.Errorf("invalid -prom-uri: %v", err)
+		}
+	}
+	if o.promConfig.Timeout <= 0 {
+		return fmt.Errorf("-prom-timeout must be positive")
+	}
+	if _, err := o.promClient(specs[0].promAddr); err != nil {
+		return err
+	}
+	if o.ingressProvider != "auto" {
+		if _, err := prom.GetIngressProviders(splitList(o.ingressProvider)); err != nil {
+			return err
+		}
+	}
+	if o.meshProvider != "auto" && o.meshProvider != "none" {
+		if _, err := prom.GetMeshProviders(splitList(o.meshProvider)); err != nil {
+			return err
+		}
+	}
+	if _, err := o.teamURLs(); err != nil {
+		return err
+	}
+	if o.webhookURL != "" || o.webhookTeamURLs != "" {
+		if _, err := notify.NewNotifier(o.notifyConfig(nil)); err != nil {
+			return err
+		}
+	}
+	if _, err := history.New(o.historySpec, nil); err != nil {
+		return err
+	}
+	if o.interval < 0 {
+		return fmt.Errorf("-interval must not be negative")
+	}
+
+	return nil
+}
+
+// promClient returns client of Prometheus at address configured by -prom-* flags
+func (o *options) promClient(address string) (*prom.Client, error) {
+	config := o.promConfig
+	config.Headers = map[string]string{}
+	for _, item := range splitList(o.promHeaders) {
+		parts := strings.SplitN(item, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, fmt.Errorf("invalid Prometheus header %q, expected name=value", item)
+		}
+		config.Headers[parts[0]] = parts[1]
+	}
+	client, err := prom.NewClient(address, config)
+	if err != nil {
+		return nil, fmt.Errorf("can't configure Prometheus client: %v", err)
+	}
+
+	return client, nil
+}
+
+// teamURLs parses -webhook-team-urls
+func (o *options) teamURLs() (map[string]string, error) {
+	teamURLs := map[string]string{}
+	for _, item := range splitList(o.webhookTeamURLs) {
+		parts := strings.SplitN(item, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid team webhook %q, expected team=url", item)
+		}
+		teamURLs[parts[0]] = parts[1]
+	}
+
+	return teamURLs, nil
+}
+
+// notifyConfig returns configuration of webhooks, teamURLs include webhooks of policies
+func (o *options) notifyConfig(teamURLs map[string]string) notify.Config {
+	return notify.Config{
+		URL:       o.webhookURL,
+		TeamURLs:  teamURLs,
+		Format:    o.webhookFormat,
+		Rate:      o.webhookRate,
+		StateFile: o.webhookState,
+	}
+}
+
+// clusterSpec is a scanned cluster and its Prometheus
+type clusterSpec struct {
+	// Kubeconfig context, empty when a single cluster is scanned
+	name     string
+	promAddr string
+}
+
+// clusterSpecs parses -clusters, without it the cluster of -context is scanned with -prom-uri
+func (o *options) clusterSpecs() ([]clusterSpec, error) {
+	items := splitList(o.clusters)
+	if len(items) == 0 {
+		return []clusterSpec{{promAddr: o.promAddr}}, nil
+	}
+	if o.context != "" {
+		return nil, fmt.Errorf("-context can't be used with -clusters")
+	}
+
+	var specs []clusterSpec
+	seen := map[string]bool{}
+	for _, item := range items {
+		parts := strings.SplitN(item, "=", 2)
+		spec := clusterSpec{name: parts[0], promAddr: o.promAddr}
+		if len(parts) == 2 {
+			spec.promAddr = parts[1]
+		}
+		if spec.name == "" || seen[spec.name] {
+			return nil, fmt.Errorf("invalid cluster %q, expected unique context=prometheus-uri or context", item)
+		}
+		seen[spec.name] = true
+		specs = append(specs, spec)
+	}
+
+	return specs, nil
+}
+
+// cluster is a connection to the scanned cluster
+type cluster struct {
+	// Kubeconfig context of the cluster in multi-cluster scans
+	name       string
+	promAddr   string
+	promClient *prom.Client
+	kClient    *kubernetes.Clientset
+	dClient    dynamic.Interface
+	ingAPI     *ukube.IngressAPI
+	policies   *policy.Store
+}
+
+// connectAll connects to clusters of -clusters, or to the single cluster
+func connectAll(o *options) ([]*cluster, error) {
+	specs, err := o.clusterSpecs()
+	if err != nil {
+		return nil, err
+	}
+
+	var clusters []*cluster
+	for _, spec := range specs {
+		c, err := connect(o, spec)
+		if err != nil {
+			if spec.name != "" {
+				return nil, fmt.Errorf("cluster %v: %v", spec.name, err)
+			}
+			return nil, err
+		}
+		clusters = append(clusters, c)
+	}
+
+	return clusters, nil
+}
+
+// connect creates clients, discovers served APIs and loads policies
+func connect(o *options, spec clusterSpec) (*cluster, error) {
+	context := o.context
+	if spec.name != "" {
+		context = spec.name
+	}
+	config, err := ukube.GetConfig(o.runOutsideCluster, o.kubeconfig, context)
+	if err != nil {
+		return nil, err
+	}
+	promClient, err := o.promClient(spec.promAddr)
+	if err != nil {
+		return nil, err
+	}
+
+	// Get tested k8s client
+	kClient, err := ukube.GetKClient(config)
+	if err != nil {
+		return nil, err
+	}
+
+	// Get dynamic client for APIs without typed clients
+	dClient, err := ukube.GetDClient(config)
+	if err != nil {
+		return nil, err
+	}
+
+	// Discover served Ingress API and limit analysis to requested ingress classes
+	ingAPI, err := ukube.NewIngressAPI(kClient, dClient)
+	if err != nil {
+		return nil, err
+	}
+	if err := ingAPI.SetScope(splitList(o.ingressClasses), splitList(o.ingressControllers)); err != nil {
+		return nil, err
+	}
+
+	// Load UselessPolicies, serve keeps watching them. Only commands acting on the cluster report their validity.
+	policies := policy.NewStore(dClient, o.act)
+	if err := policies.Sync(); err != nil {
+		klog.Warningf("Can't load policies: %v", err)
+	}
+
+	return &cluster{name: spec.name, promAddr: spec.promAddr, promClient: promClient, kClient: kClient,
+		dClient: dClient, ingAPI: ingAPI, policies: policies}, nil
+}
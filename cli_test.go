@@ -0,0 +1,37 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestParseFlags(t *testing.T) {
+	config, err := ioutil.TempFile("", "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(config.Name())
+	_, _ = config.WriteString("prom-uri: http://config:9090\nperiod: 12\njob-age: 3\n")
+	_ = config.Close()
+
+	_ = os.Setenv("USELESS_OPERATOR_PERIOD", "24")
+	_ = os.Setenv("USELESS_OPERATOR_NAMESPACE_AGE", "30")
+	defer os.Unsetenv("USELESS_OPERATOR_PERIOD")
+	defer os.Unsetenv("USELESS_OPERATOR_NAMESPACE_AGE")
+
+	o := &options{}
+	fs := newFlagSet("scan", o, detectionFlags)
+	fs.StringVar(&o.config, "config", "", "")
+	if err := parseFlags(fs, []string{"-config", config.Name(), "-namespace-age", "60"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Command line wins over environment, environment wins over the config file
+	if o.namespaceAge != 60 || o.period != 24 || o.jobAge != 3 || o.promAddr != "http://config:9090" {
+		t.Errorf("unexpected options: %+v", o)
+	}
+	if o.cronJobSuspendedAge != 30 {
+		t.Errorf("flags missing everywhere should keep defaults: %+v", o)
+	}
+}
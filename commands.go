@@ -0,0 +1,172 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"net/http"
+	_ "net/http/pprof"
+	"os"
+	"runtime"
+	"strings"
+
+	ukube "github.com/Nastradamus/useless-operator/pkg/ukubernetes"
+	"k8s.io/klog"
+)
+
+// scanCommand scans once without changing the cluster
+func scanCommand(args []string) int {
+	return oneShot("scan", args)
+}
+
+// reportCommand scans once and delivers findings
+func reportCommand(args []string) int {
+	return oneShot("report", args, deliveryFlags)
+}
+
+// remediateCommand scans once, delivers findings and acts on idle workloads
+func remediateCommand(args []string) int {
+	return oneShot("remediate", args, deliveryFlags, actionFlags)
+}
+
+// oneShot runs a single scan with flags of the command, exits with exitFindings above -max-findings
+func oneShot(name string, args []string, groups ...func(*flag.FlagSet, *options)) int {
+	o := &options{}
+	fs := newFlagSet(name, o, append([]func(*flag.FlagSet, *options){commonFlags, detectionFlags, oneShotFlags},
+		groups...)...)
+	if code, ok := parse(fs, args); !ok {
+		return code
+	}
+	if fs.NArg() > 0 {
+		fs.Usage()
+		return exitUsage
+	}
+	if err := o.validate(); err != nil {
+		_, _ = fmt.Fprintln(fs.Output(), err)
+		return exitUsage
+	}
+	startProfiling(o)
+
+	klog.V(0).Infof("Starting useless-operator %v...", version)
+	clusters, err := connectAll(o)
+	if err != nil {
+		klog.Error(err)
+		return exitError
+	}
+	scanReport, err := scan(o, clusters)
+	if err != nil {
+		klog.Error(err)
+		return exitError
+	}
+
+	// Don't exit if we want profiling (for now)
+	if o.profile {
+		fmt.Print("Program stopped. Type something to exit: ")
+		input := bufio.NewScanner(os.Stdin)
+		input.Scan()
+		fmt.Println(input.Text())
+	}
+
+	if o.maxFindings >= 0 && len(scanReport.Findings) > o.maxFindings {
+		klog.Warningf("Findings: %v, more than %v allowed", len(scanReport.Findings), o.maxFindings)
+		return exitFindings
+	}
+
+	return exitOK
+}
+
+// restoreCommand scales up workloads scaled down by the lifecycle
+func restoreCommand(args []string) int {
+	o := &options{}
+	fs := newFlagSet("restore", o, commonFlags)
+	if code, ok := parse(fs, args); !ok {
+		return code
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return exitUsage
+	}
+	var targets [][]string
+	for _, arg := range fs.Args() {
+		target := strings.Split(arg, "/")
+		if len(target) != 3 || target[0] == "" || target[1] == "" || target[2] == "" {
+			_, _ = fmt.Fprintf(fs.Output(), "Invalid workload %q, expected <namespace>/<kind>/<name>, "+
+				"e.g. shop/Deployment/cart\n", arg)
+			return exitUsage
+		}
+		targets = append(targets, target)
+	}
+
+	config, err := ukube.GetConfig(o.runOutsideCluster, o.kubeconfig, o.context)
+	if err != nil {
+		klog.Error(err)
+		return exitError
+	}
+	kClient, err := ukube.GetKClient(config)
+	if err != nil {
+		klog.Error(err)
+		return exitError
+	}
+	dClient, err := ukube.GetDClient(config)
+	if err != nil {
+		klog.Error(err)
+		return exitError
+	}
+	host, _ := os.Hostname()
+	recorder := ukube.NewEventRecorder(kClient, dClient, host)
+	defer recorder.Shutdown()
+	lc := ukube.NewLifecycle(kClient, dClient, ukube.NewMarker(kClient, dClient), recorder, ukube.LifecycleConfig{})
+
+	code := exitOK
+	for _, target := range targets {
+		if err := lc.Restore(target[0], target[1], target[2]); err != nil {
+			klog.Errorf("Can't restore %v: %v", strings.Join(target, "/"), err)
+			code = exitError
+			continue
+		}
+		klog.V(0).Infof("Restored %v", strings.Join(target, "/"))
+	}
+
+	return code
+}
+
+// versionCommand prints the version
+func versionCommand(args []string) int {
+	fs := newFlagSet("version", &options{})
+	if code, ok := parse(fs, args); !ok {
+		return code
+	}
+	fmt.Printf("useless-operator %v (%v, %v/%v)\n", version, runtime.Version(), runtime.GOOS, runtime.GOARCH)
+
+	return exitOK
+}
+
+// parse parses flags of the command, returns false with exit code if the command shouldn't run
+func parse(fs *flag.FlagSet, args []string) (int, bool) {
+	if err := parseFlags(fs, args); err == flag.ErrHelp {
+		return exitOK, false
+	} else if err != nil {
+		return exitUsage, false
+	}
+
+	return exitOK, true
+}
+
+// startProfiling serves pprof with -profile
+func startProfiling(o *options) {
+	if !o.profile {
+		return
+	}
+	klog.V(0).Infof("Profiling enabled. URL: http://0.0.0.0:6060/debug/pprof/" +
+		"Usage:\n" +
+		"http://localhost:6060/debug/pprof/\n" +
+		"go tool pprof http://0.0.0.0:6060/debug/pprof/heap\n" +
+		"go tool pprof http://0.0.0.0:6060/debug/pprof/profile?seconds=30\n" +
+		"go tool pprof http://0.0.0.0:6060/debug/pprof/block\n" +
+		"wget http://0.0.0.0:6060/debug/pprof/trace?seconds=5\n" +
+		"go tool pprof http://0.0.0.0:6060/debug/pprof/mutex\n" +
+		"To view all available profiles, open http://0.0.0.0:6060/debug/pprof/ in your browser. ")
+	go func() {
+		klog.Infoln(http.ListenAndServe("0.0.0.0:6060", nil))
+	}()
+}
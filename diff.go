@@ -0,0 +1,41 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/Nastradamus/useless-operator/pkg/report"
+)
+
+// diffCommand prints changes between two reports saved by -output
+func diffCommand(args []string) int {
+	flags := newFlagSet("diff", &options{})
+	failOnNew := flags.Bool("fail-on-new", false, "Exit with code 1 if the new report has findings the old "+
+		"one hasn't, e.g. to gate CI.")
+	if code, ok := parse(flags, args); !ok {
+		return code
+	}
+	if flags.NArg() != 2 {
+		flags.Usage()
+		return exitUsage
+	}
+
+	old, err := report.Load(flags.Arg(0))
+	if err != nil {
+		_, _ = fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+	cur, err := report.Load(flags.Arg(1))
+	if err != nil {
+		_, _ = fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+
+	d := report.Compare(old, cur)
+	d.Print(os.Stdout)
+	if *failOnNew && d.Worse() {
+		return exitFindings
+	}
+
+	return exitOK
+}
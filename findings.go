@@ -0,0 +1,263 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Nastradamus/useless-operator/pkg/policy"
+	prom "github.com/Nastradamus/useless-operator/pkg/prometheus"
+	"github.com/Nastradamus/useless-operator/pkg/report"
+	ukube "github.com/Nastradamus/useless-operator/pkg/ukubernetes"
+	v1 "k8s.io/api/core/v1"
+)
+
+// Conversions of detectors' results into report findings
+
+func danglingIngressFinding(d ukube.DanglingIngress) report.Finding {
+	subject := "default backend"
+	if d.Host != "" || d.Path != "" {
+		subject = fmt.Sprintf("host: %q, path: %q", d.Host, d.Path)
+	}
+
+	return report.Finding{
+		Category:  report.CategoryDanglingIngress,
+		Namespace: d.Namespace,
+		Kind:      "Ingress",
+		Name:      d.Ingress,
+		Subject:   subject,
+		Reason:    d.Reason,
+		Details:   map[string]string{"backend": d.Backend.String()},
+	}
+}
+
+func idleRouteFinding(route *ukube.Route) report.Finding {
+	var parents []string
+	for _, parent := range route.ParentRefs {
+		ref := fmt.Sprintf("%v %v/%v", parent.Kind, parent.Namespace, parent.Name)
+		if parent.SectionName != "" {
+			ref += "#" + parent.SectionName
+		}
+		parents = append(parents, ref)
+	}
+	var matches []string
+	for _, rule := range route.Rules {
+		matches = append(matches, rule.Matches...)
+	}
+	var backends []string
+	for _, svc := range route.BackendServices(-1) {
+		backends = append(backends, svc.Namespace+"/"+svc.Name)
+	}
+
+	return report.Finding{
+		Category:  report.CategoryIdleRoute,
+		Namespace: route.Namespace,
+		Kind:      route.Kind,
+		Name:      route.Name,
+		Reason:    "no traffic",
+		Signals:   []report.Signal{report.SignalRouteTraffic},
+		Details: map[string]string{
+			"parents":   strings.Join(parents, " "),
+			"hostnames": strings.Join(route.Hostnames, " "),
+			"matches":   strings.Join(matches, "; "),
+			"backends":  strings.Join(backends, " "),
+		},
+	}
+}
+
+func unusedVolumeFinding(v ukube.UnusedVolume) report.Finding {
+	f := report.Finding{
+		Category:  report.CategoryUnusedVolume,
+		Namespace: v.Namespace,
+		Kind:      v.Kind,
+		Name:      v.Name,
+		Reason:    v.Reason,
+		Storage:   v.Capacity,
+		Details:   map[string]string{"storageClass": v.StorageClass},
+	}
+	if len(v.Pods) > 0 {
+		f.Details["pods"] = strings.Join(v.Pods, " ")
+	}
+	if v.Namespace != "" {
+		f.Command = fmt.Sprintf("kubectl -n %v delete pvc %v", v.Namespace, v.Name)
+	} else {
+		f.Command = fmt.Sprintf("kubectl delete pv %v", v.Name)
+	}
+
+	return f
+}
+
+func unreferencedConfigFinding(c ukube.UnreferencedObject) report.Finding {
+	f := report.Finding{
+		Category:  report.CategoryUnreferencedConfig,
+		Namespace: c.Namespace,
+		Kind:      c.Kind,
+		Name:      c.Name,
+		Reason:    "not referenced",
+		Details: map[string]string{
+			"ageDays": strconv.Itoa(int(c.Age.Hours() / 24)),
+			"sizeKB":  fmt.Sprintf("%.1f", float64(c.Size)/1024),
+		},
+	}
+	if c.Type != "" {
+		f.Details["type"] = c.Type
+	}
+	f.Command = fmt.Sprintf("kubectl -n %v delete %v %v", c.Namespace, strings.ToLower(c.Kind), c.Name)
+
+	return f
+}
+
+func staleJobFinding(j ukube.StaleJob) report.Finding {
+	return report.Finding{
+		Category:  report.CategoryStaleJob,
+		Namespace: j.Namespace,
+		Kind:      j.Kind,
+		Name:      j.Name,
+		Reason:    j.Reason,
+		CPU:       j.CPU,
+		Memory:    j.Memory,
+		Command:   j.Command(),
+		Details: map[string]string{
+			"ageDays": strconv.Itoa(int(j.Age.Hours() / 24)),
+			"pods":    strconv.Itoa(j.Pods),
+		},
+	}
+}
+
+func pinnedHPAFinding(hpa ukube.HPA, peakUtilization float64) report.Finding {
+	f := report.Finding{
+		Category:  report.CategoryPinnedHPA,
+		Namespace: hpa.Namespace,
+		Kind:      "HorizontalPodAutoscaler",
+		Name:      hpa.Name,
+		Reason:    "pinned at minReplicas with idle target",
+		Details: map[string]string{
+			"target":   hpa.Target.Kind + "/" + hpa.Target.Name,
+			"replicas": fmt.Sprintf("min %v, max %v, current %v", hpa.MinReplicas, hpa.MaxReplicas, hpa.CurrentReplicas),
+		},
+	}
+	f.Details["peakCPUUtilization"] = fmt.Sprintf("%.0f%%", peakUtilization)
+	if hpa.MinReplicas > 1 {
+		f.Command = fmt.Sprintf(`kubectl -n %v patch hpa %v -p '{"spec":{"minReplicas":1}}'`, hpa.Namespace, hpa.Name)
+	}
+
+	return f
+}
+
+func abandonedNamespaceFinding(ns ukube.AbandonedNamespace) report.Finding {
+	f := report.Finding{
+		Kind:     "Namespace",
+		Category: report.CategoryAbandonedNamespace,
+		Name:     ns.Name,
+		Reason:   strings.Join(ns.Reasons, ", "),
+		CPU:      ns.CPU,
+		Memory:   ns.Memory,
+		Details: map[string]string{
+			"workloads":    fmt.Sprintf("%v (idle: %v)", ns.Workloads, ns.IdleWorkloads),
+			"pods":         strconv.Itoa(ns.Pods),
+			"lastModified": ns.LastModified.Format("2006-01-02"),
+		},
+	}
+	for key, value := range ns.Owners {
+		f.Details[key] = value
+	}
+
+	return f
+}
+
+// recordFindingEvent records event on flagged Deployment, StatefulSet or Ingress describing the finding.
+// Returns false if events aren't recorded on the finding's kind.
+func recordFindingEvent(recorder *ukube.EventRecorder, f *report.Finding, observedHours int) (bool, error) {
+	var reason, message string
+	switch {
+	case f.Category == report.CategoryIdleWorkload && (f.Kind == ukube.KindDeployment || f.Kind == ukube.KindStatefulSet):
+		reason = ukube.EventReasonIdle
+		message = fmt.Sprintf("No traffic for the observed %vh (signals: %v), reclaimable requests: CPU: %v, "+
+			"memory (MB): %v, pods: %v", observedHours, report.SignalsString(f.Signals), float64(f.CPU)/1000,
+			f.Memory/1024/1024, f.Details["pods"])
+	case f.Category == report.CategoryDanglingIngress:
+		reason = ukube.EventReasonDangling
+		message = fmt.Sprintf("Backend %v of %v can't serve traffic: %v", f.Details["backend"], f.Subject, f.Reason)
+	default:
+		return false, nil
+	}
+
+	ref, err := recorder.Reference(f.Namespace, f.Kind, f.Name)
+	if err != nil {
+		return true, err
+	}
+
+	recorder.Eventf(ref, v1.EventTypeWarning, reason, "%v", message)
+
+	return true, nil
+}
+
+// recordIdleIngressEvent records event on the Ingress telling its paths without traffic for the observed window
+func recordIdleIngressEvent(recorder *ukube.EventRecorder, idle *idleIngress, observedHours int) error {
+	ref, err := recorder.Reference(idle.Namespace, "Ingress", idle.Name)
+	if err != nil {
+		return err
+	}
+	recorder.Eventf(ref, v1.EventTypeWarning, ukube.EventReasonIdle, "No traffic for the observed %vh: %v",
+		observedHours, strings.Join(idle.Paths, ", "))
+
+	return nil
+}
+
+// filterStaleJobs drops jobs younger than ages of their policies and CronJobs with less failed runs
+func filterStaleJobs(jobs []ukube.StaleJob, settings *policy.Resolver) []ukube.StaleJob {
+	var filtered []ukube.StaleJob
+	for _, j := range jobs {
+		s := settings.Settings(j.Namespace, j.Kind, j.Name)
+		if j.Reason == ukube.ReasonJobFinished && j.Age < s.JobAge ||
+			j.Reason == ukube.ReasonCronJobSuspended && j.Age < s.CronJobSuspendedAge ||
+			strings.HasPrefix(j.Reason, ukube.ReasonCronJobAlwaysFails) && !j.RunsFailed(s.CronJobFailedRuns, s.JobAge) {
+			continue
+		}
+		filtered = append(filtered, j)
+	}
+
+	return filtered
+}
+
+// filterPinnedHPAs returns pinned HPAs whose highest utilization is below the utilization of their policies
+func filterPinnedHPAs(hpas []ukube.HPA, pinned map[prom.Namespace]map[prom.Element]float64,
+	settings *policy.Resolver) []ukube.HPA {
+
+	var filtered []ukube.HPA
+	for _, hpa := range hpas {
+		peak, ok := pinned[prom.Namespace(hpa.Namespace)][prom.Element(hpa.Name)]
+		if !ok || peak >= float64(settings.Settings(hpa.Namespace, "HorizontalPodAutoscaler",
+			hpa.Name).HPAUtilization) {
+			continue
+		}
+		filtered = append(filtered, hpa)
+	}
+
+	return filtered
+}
+
+// filterAbandonedNamespaces drops "no modifications" reason of namespaces modified within age of their policies
+func filterAbandonedNamespaces(namespaces []ukube.AbandonedNamespace,
+	settings *policy.Resolver) []ukube.AbandonedNamespace {
+
+	var filtered []ukube.AbandonedNamespace
+	for _, ns := range namespaces {
+		s := settings.Settings("", "Namespace", ns.Name)
+		var reasons []string
+		for _, reason := range ns.Reasons {
+			notModified := s.NamespaceAge > 0 && time.Since(ns.LastModified) >= s.NamespaceAge
+			if reason == ukube.ReasonNotModified && !notModified {
+				continue
+			}
+			reasons = append(reasons, reason)
+		}
+		if len(reasons) > 0 {
+			ns.Reasons = reasons
+			filtered = append(filtered, ns)
+		}
+	}
+
+	return filtered
+}
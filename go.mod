@@ -3,10 +3,14 @@ module github.com/Nastradamus/useless-operator
 go 1.13
 
 require (
+	github.com/golang/groupcache v0.0.0-20191027212112-611e8accdfc9 // indirect
 	github.com/prometheus/client_golang v1.3.0
+	github.com/prometheus/common v0.7.0
+	golang.org/x/time v0.0.0-20161028155119-f51c12702a4d
 	k8s.io/api v0.15.9
 	k8s.io/apimachinery v0.15.9
 	k8s.io/client-go v0.15.9
 	k8s.io/klog v0.3.1
+	k8s.io/kube-openapi v0.0.0-20190816220812-743ec37842bf // indirect
 	k8s.io/metrics v0.15.9 // indirect
 )
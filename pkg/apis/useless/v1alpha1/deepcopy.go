@@ -0,0 +1,390 @@
+package v1alpha1
+
+import (
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// DeepCopyInto copies the policy into out
+func (in *UselessPolicy) DeepCopyInto(out *UselessPolicy) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	out.Status = in.Status
+}
+
+// DeepCopy returns copy of the policy
+func (in *UselessPolicy) DeepCopy() *UselessPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(UselessPolicy)
+	in.DeepCopyInto(out)
+
+	return out
+}
+
+// DeepCopyObject returns copy of the policy as runtime.Object
+func (in *UselessPolicy) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+
+	return nil
+}
+
+// DeepCopyInto copies the list into out
+func (in *UselessPolicyList) DeepCopyInto(out *UselessPolicyList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		out.Items = make([]UselessPolicy, len(in.Items))
+		for i := range in.Items {
+			in.Items[i].DeepCopyInto(&out.Items[i])
+		}
+	}
+}
+
+// DeepCopy returns copy of the list
+func (in *UselessPolicyList) DeepCopy() *UselessPolicyList {
+	if in == nil {
+		return nil
+	}
+	out := new(UselessPolicyList)
+	in.DeepCopyInto(out)
+
+	return out
+}
+
+// DeepCopyObject returns copy of the list as runtime.Object
+func (in *UselessPolicyList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+
+	return nil
+}
+
+// DeepCopyInto copies the policy into out
+func (in *ClusterUselessPolicy) DeepCopyInto(out *ClusterUselessPolicy) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	out.Status = in.Status
+}
+
+// DeepCopy returns copy of the policy
+func (in *ClusterUselessPolicy) DeepCopy() *ClusterUselessPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterUselessPolicy)
+	in.DeepCopyInto(out)
+
+	return out
+}
+
+// DeepCopyObject returns copy of the policy as runtime.Object
+func (in *ClusterUselessPolicy) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+
+	return nil
+}
+
+// DeepCopyInto copies the list into out
+func (in *ClusterUselessPolicyList) DeepCopyInto(out *ClusterUselessPolicyList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		out.Items = make([]ClusterUselessPolicy, len(in.Items))
+		for i := range in.Items {
+			in.Items[i].DeepCopyInto(&out.Items[i])
+		}
+	}
+}
+
+// DeepCopy returns copy of the list
+func (in *ClusterUselessPolicyList) DeepCopy() *ClusterUselessPolicyList {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterUselessPolicyList)
+	in.DeepCopyInto(out)
+
+	return out
+}
+
+// DeepCopyObject returns copy of the list as runtime.Object
+func (in *ClusterUselessPolicyList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+
+	return nil
+}
+
+// DeepCopyInto copies the spec into out
+func (in *UselessPolicySpec) DeepCopyInto(out *UselessPolicySpec) {
+	*out = *in
+	if in.Selector != nil {
+		out.Selector = in.Selector.DeepCopy()
+	}
+	if in.NamespaceSelector != nil {
+		out.NamespaceSelector = in.NamespaceSelector.DeepCopy()
+	}
+	if in.Detectors != nil {
+		out.Detectors = make([]string, len(in.Detectors))
+		copy(out.Detectors, in.Detectors)
+	}
+	out.ObservationPeriod = copyDuration(in.ObservationPeriod)
+	in.Thresholds.DeepCopyInto(&out.Thresholds)
+	in.Lifecycle.DeepCopyInto(&out.Lifecycle)
+	out.Notification = in.Notification
+}
+
+// DeepCopy returns copy of the spec
+func (in *UselessPolicySpec) DeepCopy() *UselessPolicySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(UselessPolicySpec)
+	in.DeepCopyInto(out)
+
+	return out
+}
+
+// DeepCopyInto copies the thresholds into out
+func (in *Thresholds) DeepCopyInto(out *Thresholds) {
+	*out = *in
+	out.JobAge = copyDuration(in.JobAge)
+	out.CronJobSuspendedAge = copyDuration(in.CronJobSuspendedAge)
+	out.CronJobFailedRuns = copyInt32(in.CronJobFailedRuns)
+	out.HPAUtilization = copyInt32(in.HPAUtilization)
+	out.NamespaceAge = copyDuration(in.NamespaceAge)
+}
+
+// DeepCopyInto copies the lifecycle into out
+func (in *Lifecycle) DeepCopyInto(out *Lifecycle) {
+	*out = *in
+	out.WarnAfter = copyDuration(in.WarnAfter)
+	out.ScaleDownAfter = copyDuration(in.ScaleDownAfter)
+	out.DeleteAfter = copyDuration(in.DeleteAfter)
+}
+
+// copyDuration returns copy of optional duration
+func copyDuration(in *metav1.Duration) *metav1.Duration {
+	if in == nil {
+		return nil
+	}
+	out := *in
+
+	return &out
+}
+
+// copyInt32 returns copy of optional number
+func copyInt32(in *int32) *int32 {
+	if in == nil {
+		return nil
+	}
+	out := *in
+
+	return &out
+}
+
+// DeepCopyInto copies the report into out
+func (in *UselessReport) DeepCopyInto(out *UselessReport) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy returns copy of the report
+func (in *UselessReport) DeepCopy() *UselessReport {
+	if in == nil {
+		return nil
+	}
+	out := new(UselessReport)
+	in.DeepCopyInto(out)
+
+	return out
+}
+
+// DeepCopyObject returns copy of the report as runtime.Object
+func (in *UselessReport) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+
+	return nil
+}
+
+// DeepCopyInto copies the list into out
+func (in *UselessReportList) DeepCopyInto(out *UselessReportList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		out.Items = make([]UselessReport, len(in.Items))
+		for i := range in.Items {
+			in.Items[i].DeepCopyInto(&out.Items[i])
+		}
+	}
+}
+
+// DeepCopy returns copy of the list
+func (in *UselessReportList) DeepCopy() *UselessReportList {
+	if in == nil {
+		return nil
+	}
+	out := new(UselessReportList)
+	in.DeepCopyInto(out)
+
+	return out
+}
+
+// DeepCopyObject returns copy of the list as runtime.Object
+func (in *UselessReportList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+
+	return nil
+}
+
+// DeepCopyInto copies the report into out
+func (in *ClusterUselessReport) DeepCopyInto(out *ClusterUselessReport) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy returns copy of the report
+func (in *ClusterUselessReport) DeepCopy() *ClusterUselessReport {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterUselessReport)
+	in.DeepCopyInto(out)
+
+	return out
+}
+
+// DeepCopyObject returns copy of the report as runtime.Object
+func (in *ClusterUselessReport) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+
+	return nil
+}
+
+// DeepCopyInto copies the list into out
+func (in *ClusterUselessReportList) DeepCopyInto(out *ClusterUselessReportList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		out.Items = make([]ClusterUselessReport, len(in.Items))
+		for i := range in.Items {
+			in.Items[i].DeepCopyInto(&out.Items[i])
+		}
+	}
+}
+
+// DeepCopy returns copy of the list
+func (in *ClusterUselessReportList) DeepCopy() *ClusterUselessReportList {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterUselessReportList)
+	in.DeepCopyInto(out)
+
+	return out
+}
+
+// DeepCopyObject returns copy of the list as runtime.Object
+func (in *ClusterUselessReportList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+
+	return nil
+}
+
+// DeepCopyInto copies the status into out
+func (in *UselessReportStatus) DeepCopyInto(out *UselessReportStatus) {
+	*out = *in
+	in.ScanTime.DeepCopyInto(&out.ScanTime)
+	in.Summary.DeepCopyInto(&out.Summary)
+	out.Findings = copyFindings(in.Findings)
+}
+
+// DeepCopyInto copies the status into out
+func (in *ClusterUselessReportStatus) DeepCopyInto(out *ClusterUselessReportStatus) {
+	*out = *in
+	in.ScanTime.DeepCopyInto(&out.ScanTime)
+	in.Summary.DeepCopyInto(&out.Summary)
+	if in.Namespaces != nil {
+		out.Namespaces = make([]NamespaceSummary, len(in.Namespaces))
+		for i := range in.Namespaces {
+			out.Namespaces[i].Namespace = in.Namespaces[i].Namespace
+			in.Namespaces[i].ReportSummary.DeepCopyInto(&out.Namespaces[i].ReportSummary)
+		}
+	}
+	out.Findings = copyFindings(in.Findings)
+}
+
+// DeepCopyInto copies the summary into out
+func (in *ReportSummary) DeepCopyInto(out *ReportSummary) {
+	*out = *in
+	out.CPU = in.CPU.DeepCopy()
+	out.Memory = in.Memory.DeepCopy()
+	out.Storage = in.Storage.DeepCopy()
+}
+
+// DeepCopyInto copies the finding into out
+func (in *ReportFinding) DeepCopyInto(out *ReportFinding) {
+	*out = *in
+	if in.Signals != nil {
+		out.Signals = make([]string, len(in.Signals))
+		copy(out.Signals, in.Signals)
+	}
+	if in.FirstSeen != nil {
+		out.FirstSeen = in.FirstSeen.DeepCopy()
+	}
+	out.CPU = copyQuantity(in.CPU)
+	out.Memory = copyQuantity(in.Memory)
+	out.Storage = copyQuantity(in.Storage)
+}
+
+// copyFindings returns deep copy of findings
+func copyFindings(in []ReportFinding) []ReportFinding {
+	if in == nil {
+		return nil
+	}
+	out := make([]ReportFinding, len(in))
+	for i := range in {
+		in[i].DeepCopyInto(&out[i])
+	}
+
+	return out
+}
+
+// copyQuantity returns copy of optional quantity
+func copyQuantity(in *resource.Quantity) *resource.Quantity {
+	if in == nil {
+		return nil
+	}
+	out := in.DeepCopy()
+
+	return &out
+}
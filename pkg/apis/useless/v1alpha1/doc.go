@@ -0,0 +1,2 @@
+// Package v1alpha1 contains API types of useless-operator's custom resources
+package v1alpha1
@@ -0,0 +1,43 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// GroupName of useless-operator's custom resources
+const GroupName = "useless-operator.io"
+
+// SchemeGroupVersion is group version of the types
+var SchemeGroupVersion = schema.GroupVersion{Group: GroupName, Version: "v1alpha1"}
+
+// Resources of the types
+var (
+	UselessPolicyResource        = SchemeGroupVersion.WithResource("uselesspolicies")
+	ClusterUselessPolicyResource = SchemeGroupVersion.WithResource("clusteruselesspolicies")
+	UselessReportResource        = SchemeGroupVersion.WithResource("uselessreports")
+	ClusterUselessReportResource = SchemeGroupVersion.WithResource("clusteruselessreports")
+)
+
+var (
+	SchemeBuilder = runtime.NewSchemeBuilder(addKnownTypes)
+	AddToScheme   = SchemeBuilder.AddToScheme
+)
+
+// addKnownTypes adds the types to the scheme
+func addKnownTypes(scheme *runtime.Scheme) error {
+	scheme.AddKnownTypes(SchemeGroupVersion,
+		&UselessPolicy{},
+		&UselessPolicyList{},
+		&ClusterUselessPolicy{},
+		&ClusterUselessPolicyList{},
+		&UselessReport{},
+		&UselessReportList{},
+		&ClusterUselessReport{},
+		&ClusterUselessReportList{},
+	)
+	metav1.AddToGroupVersion(scheme, SchemeGroupVersion)
+
+	return nil
+}
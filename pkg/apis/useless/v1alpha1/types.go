@@ -0,0 +1,214 @@
+package v1alpha1
+
+import (
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Actions taken on idle workloads
+const (
+	// Findings are only reported
+	ActionReport = "report"
+	// Idle workloads are marked with annotations and state label
+	ActionMark = "mark"
+	// Idle workloads are warned, scaled down and optionally deleted
+	ActionLifecycle = "lifecycle"
+)
+
+// Detectors which can be enabled, named after report categories
+var Detectors = []string{
+	"idle-workload",
+	"idle-route",
+	"dangling-ingress",
+	"unused-volume",
+	"unreferenced-config",
+	"stale-job",
+	"pinned-hpa",
+	"abandoned-namespace",
+}
+
+// UselessPolicy configures detection and actions for objects in its namespace
+type UselessPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   UselessPolicySpec   `json:"spec"`
+	Status UselessPolicyStatus `json:"status,omitempty"`
+}
+
+// UselessPolicyList is a list of UselessPolicies
+type UselessPolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	Items []UselessPolicy `json:"items"`
+}
+
+// ClusterUselessPolicy configures detection and actions for objects in namespaces matching its namespace selector
+type ClusterUselessPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   UselessPolicySpec   `json:"spec"`
+	Status UselessPolicyStatus `json:"status,omitempty"`
+}
+
+// ClusterUselessPolicyList is a list of ClusterUselessPolicies
+type ClusterUselessPolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	Items []ClusterUselessPolicy `json:"items"`
+}
+
+// UselessPolicySpec is configuration of a policy. Unset fields fall back to command line flags.
+type UselessPolicySpec struct {
+	// Objects the policy applies to, empty selector selects all objects
+	Selector *metav1.LabelSelector `json:"selector,omitempty"`
+	// Namespaces the policy applies to, ClusterUselessPolicy only. Empty selector selects all namespaces.
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+
+	// Enabled detectors (see Detectors), empty list enables all of them
+	Detectors []string `json:"detectors,omitempty"`
+
+	// Period traffic is observed for. Prometheus is queried once for all workloads,
+	// so the longest period of all policies is used.
+	ObservationPeriod *metav1.Duration `json:"observationPeriod,omitempty"`
+
+	Thresholds Thresholds `json:"thresholds,omitempty"`
+
+	Lifecycle Lifecycle `json:"lifecycle,omitempty"`
+
+	Notification Notification `json:"notification,omitempty"`
+}
+
+// Thresholds of detectors
+type Thresholds struct {
+	// Jobs finished longer ago are stale
+	JobAge *metav1.Duration `json:"jobAge,omitempty"`
+	// CronJobs suspended and not scheduled for longer are stale
+	CronJobSuspendedAge *metav1.Duration `json:"cronJobSuspendedAge,omitempty"`
+	// CronJobs whose last runs all failed are stale. Jobs are queried once for all CronJobs,
+	// so the highest number of all policies is used.
+	CronJobFailedRuns *int32 `json:"cronJobFailedRuns,omitempty"`
+	// HPAs pinned at minReplicas with CPU utilization (percent of requests) below this are reported.
+	// Prometheus is queried once for all HPAs, so the lowest utilization of all policies is used.
+	HPAUtilization *int32 `json:"hpaUtilization,omitempty"`
+	// Namespaces without modifications for longer are abandoned
+	NamespaceAge *metav1.Duration `json:"namespaceAge,omitempty"`
+}
+
+// Lifecycle configures actions on idle workloads
+type Lifecycle struct {
+	// report, mark or lifecycle
+	Action string `json:"action,omitempty"`
+	// Since the workload is idle until it's warned
+	WarnAfter *metav1.Duration `json:"warnAfter,omitempty"`
+	// Since the warning until the workload is scaled down
+	ScaleDownAfter *metav1.Duration `json:"scaleDownAfter,omitempty"`
+	// Since scaling down until the workload is deleted, zero disables deletion
+	DeleteAfter *metav1.Duration `json:"deleteAfter,omitempty"`
+}
+
+// Notification configures where findings are sent
+type Notification struct {
+	// Findings are attributed to this owner instead of the one found by owner keys
+	Owner string `json:"owner,omitempty"`
+	// Webhook receiving the owner's summaries
+	Webhook string `json:"webhook,omitempty"`
+}
+
+// UselessPolicyStatus is observed state of a policy
+type UselessPolicyStatus struct {
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// Whether the spec is valid
+	Valid bool `json:"valid"`
+	// Validation errors
+	Message string `json:"message,omitempty"`
+}
+
+// UselessReport lists findings of the last scan in its namespace
+type UselessReport struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Status UselessReportStatus `json:"status,omitempty"`
+}
+
+// UselessReportList is a list of UselessReports
+type UselessReportList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	Items []UselessReport `json:"items"`
+}
+
+// UselessReportStatus is result of the last scan of a namespace
+type UselessReportStatus struct {
+	ScanTime metav1.Time `json:"scanTime"`
+	// Period traffic was observed for
+	ObservedWindow metav1.Duration `json:"observedWindow"`
+	Summary        ReportSummary   `json:"summary"`
+	Findings       []ReportFinding `json:"findings,omitempty"`
+}
+
+// ClusterUselessReport summarizes findings of the last scan in all namespaces
+type ClusterUselessReport struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Status ClusterUselessReportStatus `json:"status,omitempty"`
+}
+
+// ClusterUselessReportList is a list of ClusterUselessReports
+type ClusterUselessReportList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	Items []ClusterUselessReport `json:"items"`
+}
+
+// ClusterUselessReportStatus is result of the last scan of the cluster
+type ClusterUselessReportStatus struct {
+	ScanTime       metav1.Time     `json:"scanTime"`
+	ObservedWindow metav1.Duration `json:"observedWindow"`
+	Summary        ReportSummary   `json:"summary"`
+	// Summaries of namespaces with findings
+	Namespaces []NamespaceSummary `json:"namespaces,omitempty"`
+	// Findings of cluster-scoped objects
+	Findings []ReportFinding `json:"findings,omitempty"`
+}
+
+// ReportSummary sums up findings and their reclaimable resources
+type ReportSummary struct {
+	Findings int               `json:"findings"`
+	CPU      resource.Quantity `json:"cpu"`
+	Memory   resource.Quantity `json:"memory"`
+	Storage  resource.Quantity `json:"storage"`
+}
+
+// NamespaceSummary sums up findings of a namespace
+type NamespaceSummary struct {
+	Namespace     string `json:"namespace"`
+	ReportSummary `json:",inline"`
+}
+
+// ReportFinding is an object considered useless
+type ReportFinding struct {
+	Category string   `json:"category"`
+	Kind     string   `json:"kind"`
+	Name     string   `json:"name"`
+	Subject  string   `json:"subject,omitempty"`
+	Reason   string   `json:"reason"`
+	Signals  []string `json:"signals,omitempty"`
+	Owner    string   `json:"owner,omitempty"`
+	State    string   `json:"state,omitempty"`
+	// Start of the streak of consecutive scans finding the object, with scan history only
+	FirstSeen *metav1.Time `json:"firstSeen,omitempty"`
+	Streak    int          `json:"streak,omitempty"`
+	// Reclaimable resources
+	CPU     *resource.Quantity `json:"cpu,omitempty"`
+	Memory  *resource.Quantity `json:"memory,omitempty"`
+	Storage *resource.Quantity `json:"storage,omitempty"`
+	Command string             `json:"command,omitempty"`
+}
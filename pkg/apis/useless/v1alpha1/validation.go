@@ -0,0 +1,103 @@
+package v1alpha1
+
+import (
+	"net/url"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	metav1validation "k8s.io/apimachinery/pkg/apis/meta/v1/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// Validate checks the policy
+func (p *UselessPolicy) Validate() field.ErrorList {
+	specPath := field.NewPath("spec")
+	errs := p.Spec.Validate(specPath)
+	if p.Spec.NamespaceSelector != nil {
+		errs = append(errs, field.Forbidden(specPath.Child("namespaceSelector"),
+			"namespaced policies apply to their namespace only"))
+	}
+
+	return errs
+}
+
+// Validate checks the policy
+func (p *ClusterUselessPolicy) Validate() field.ErrorList {
+	return p.Spec.Validate(field.NewPath("spec"))
+}
+
+// Validate checks the spec
+func (spec *UselessPolicySpec) Validate(path *field.Path) field.ErrorList {
+	var errs field.ErrorList
+
+	if spec.Selector != nil {
+		errs = append(errs, metav1validation.ValidateLabelSelector(spec.Selector, path.Child("selector"))...)
+	}
+	if spec.NamespaceSelector != nil {
+		errs = append(errs, metav1validation.ValidateLabelSelector(spec.NamespaceSelector,
+			path.Child("namespaceSelector"))...)
+	}
+
+	known := map[string]bool{}
+	for _, detector := range Detectors {
+		known[detector] = true
+	}
+	for i, detector := range spec.Detectors {
+		if !known[detector] {
+			errs = append(errs, field.NotSupported(path.Child("detectors").Index(i), detector, Detectors))
+		}
+	}
+
+	errs = append(errs, validatePositive(spec.ObservationPeriod, path.Child("observationPeriod"))...)
+
+	thresholdsPath := path.Child("thresholds")
+	errs = append(errs, validatePositive(spec.Thresholds.JobAge, thresholdsPath.Child("jobAge"))...)
+	errs = append(errs, validatePositive(spec.Thresholds.CronJobSuspendedAge,
+		thresholdsPath.Child("cronJobSuspendedAge"))...)
+	errs = append(errs, validatePositive(spec.Thresholds.NamespaceAge, thresholdsPath.Child("namespaceAge"))...)
+	if runs := spec.Thresholds.CronJobFailedRuns; runs != nil && *runs < 0 {
+		errs = append(errs, field.Invalid(thresholdsPath.Child("cronJobFailedRuns"), *runs,
+			"must not be negative"))
+	}
+	if utilization := spec.Thresholds.HPAUtilization; utilization != nil &&
+		(*utilization < 0 || *utilization > 100) {
+		errs = append(errs, field.Invalid(thresholdsPath.Child("hpaUtilization"), *utilization,
+			"must be between 0 and 100"))
+	}
+
+	lifecyclePath := path.Child("lifecycle")
+	switch spec.Lifecycle.Action {
+	case "", ActionReport, ActionMark, ActionLifecycle:
+	default:
+		errs = append(errs, field.NotSupported(lifecyclePath.Child("action"), spec.Lifecycle.Action,
+			[]string{ActionReport, ActionMark, ActionLifecycle}))
+	}
+	errs = append(errs, validatePositive(spec.Lifecycle.WarnAfter, lifecyclePath.Child("warnAfter"))...)
+	errs = append(errs, validatePositive(spec.Lifecycle.ScaleDownAfter, lifecyclePath.Child("scaleDownAfter"))...)
+	if d := spec.Lifecycle.DeleteAfter; d != nil && d.Duration < 0 {
+		errs = append(errs, field.Invalid(lifecyclePath.Child("deleteAfter"), d.Duration.String(),
+			"must not be negative"))
+	}
+
+	if webhook := spec.Notification.Webhook; webhook != "" {
+		u, err := url.Parse(webhook)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs = append(errs, field.Invalid(path.Child("notification", "webhook"), webhook,
+				"must be http or https URL"))
+		}
+		if spec.Notification.Owner == "" {
+			errs = append(errs, field.Required(path.Child("notification", "owner"),
+				"webhook receives summaries of the owner"))
+		}
+	}
+
+	return errs
+}
+
+// validatePositive checks optional duration is positive
+func validatePositive(d *metav1.Duration, path *field.Path) field.ErrorList {
+	if d != nil && d.Duration <= 0 {
+		return field.ErrorList{field.Invalid(path, d.Duration.String(), "must be positive")}
+	}
+
+	return nil
+}
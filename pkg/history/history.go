@@ -0,0 +1,167 @@
+package history
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Nastradamus/useless-operator/pkg/report"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Number of scans kept for trends, so the history fits into a ConfigMap
+const MaxScans = 100
+
+// Number of tracked findings, an entry takes ~200 bytes so the history fits into 1 MiB of a ConfigMap.
+// The longest seen findings are kept, others are tracked as new again.
+const MaxFindings = 4000
+
+// Store persists history between scans
+type Store interface {
+	// Load returns saved history, empty history if nothing was saved yet
+	Load() (*History, error)
+	Save(h *History) error
+}
+
+// History of scans and findings of the last scan
+type History struct {
+	Scans []Scan `json:"scans"`
+	// Findings of the last scan by their keys
+	Findings map[string]*Entry `json:"findings"`
+}
+
+// Scan sums up findings of a scan
+type Scan struct {
+	Time     time.Time `json:"time"`
+	Findings int       `json:"findings"`
+	CPU      int64     `json:"cpuMilli"`
+	Memory   int64     `json:"memoryBytes"`
+	Storage  int64     `json:"storageBytes"`
+}
+
+// Entry tracks a finding across consecutive scans
+type Entry struct {
+	FirstSeen time.Time `json:"firstSeen"`
+	// Number of consecutive scans the finding was found by
+	Streak int `json:"streak"`
+}
+
+// Diff compares a scan with the previous one
+type Diff struct {
+	// Zero if there is no previous scan
+	Previous Scan
+	Current  Scan
+	New      []string
+	Resolved []string
+}
+
+// New returns store by spec: "file:<path>", "configmap:<namespace>/<name>" or empty to disable history
+func New(spec string, kClient kubernetes.Interface) (Store, error) {
+	if spec == "" {
+		return nil, nil
+	}
+	parts := strings.SplitN(spec, ":", 2)
+	if len(parts) != 2 || parts[1] == "" {
+		return nil, fmt.Errorf("invalid history store %q, expected file:<path> or configmap:<namespace>/<name>",
+			spec)
+	}
+
+	switch parts[0] {
+	case "file":
+		return NewFileStore(parts[1]), nil
+	case "configmap":
+		ref := strings.SplitN(parts[1], "/", 2)
+		if len(ref) != 2 || ref[0] == "" || ref[1] == "" {
+			return nil, fmt.Errorf("invalid history ConfigMap %q, expected <namespace>/<name>", parts[1])
+		}
+		return NewConfigMapStore(kClient, ref[0], ref[1]), nil
+	}
+
+	return nil, fmt.Errorf("unknown history store %q, expected file or configmap", parts[0])
+}
+
+// Record adds the scan to the history and annotates findings with first seen time and streak
+func (h *History) Record(r *report.Report, now time.Time) Diff {
+	diff := Diff{Current: Scan{Time: now}}
+	if len(h.Scans) > 0 {
+		diff.Previous = h.Scans[len(h.Scans)-1]
+	}
+
+	findings := map[string]*Entry{}
+	for i := range r.Findings {
+		f := &r.Findings[i]
+		key := f.Key()
+		entry, ok := findings[key]
+		if !ok {
+			if previous, seen := h.Findings[key]; seen {
+				entry = &Entry{FirstSeen: previous.FirstSeen, Streak: previous.Streak + 1}
+			} else {
+				entry = &Entry{FirstSeen: now, Streak: 1}
+				diff.New = append(diff.New, key)
+			}
+			findings[key] = entry
+		}
+		firstSeen := entry.FirstSeen
+		f.FirstSeen, f.Streak = &firstSeen, entry.Streak
+
+		diff.Current.Findings++
+		diff.Current.CPU += f.CPU
+		diff.Current.Memory += f.Memory
+		diff.Current.Storage += f.Storage
+	}
+	for key := range h.Findings {
+		if _, ok := findings[key]; !ok {
+			diff.Resolved = append(diff.Resolved, key)
+		}
+	}
+	sort.Strings(diff.New)
+	sort.Strings(diff.Resolved)
+
+	h.Findings = limitFindings(findings, MaxFindings)
+	h.Scans = append(h.Scans, diff.Current)
+	if len(h.Scans) > MaxScans {
+		h.Scans = h.Scans[len(h.Scans)-MaxScans:]
+	}
+
+	return diff
+}
+
+// limitFindings keeps at most max findings seen first
+func limitFindings(findings map[string]*Entry, max int) map[string]*Entry {
+	if len(findings) <= max {
+		return findings
+	}
+
+	keys := make([]string, 0, len(findings))
+	for key := range findings {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := findings[keys[i]], findings[keys[j]]
+		if !a.FirstSeen.Equal(b.FirstSeen) {
+			return a.FirstSeen.Before(b.FirstSeen)
+		}
+		return keys[i] < keys[j]
+	})
+
+	limited := make(map[string]*Entry, max)
+	for _, key := range keys[:max] {
+		limited[key] = findings[key]
+	}
+
+	return limited
+}
+
+// String returns one line summary of changes since the previous scan
+func (d *Diff) String() string {
+	if d.Previous.Time.IsZero() {
+		return fmt.Sprintf("first recorded scan, findings: %v", d.Current.Findings)
+	}
+
+	return fmt.Sprintf("since %v: findings: %v (new: %v, resolved: %v), requests: CPU: %+.3f, "+
+		"memory (MB): %+d, storage (GB): %+.2f",
+		d.Previous.Time.Format(time.RFC3339), d.Current.Findings, len(d.New), len(d.Resolved),
+		float64(d.Current.CPU-d.Previous.CPU)/1000, (d.Current.Memory-d.Previous.Memory)/1024/1024,
+		float64(d.Current.Storage-d.Previous.Storage)/1024/1024/1024)
+}
@@ -0,0 +1,118 @@
+package history
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Nastradamus/useless-operator/pkg/report"
+)
+
+func scanReport(findings ...report.Finding) *report.Report {
+	r := &report.Report{}
+	r.Add(findings...)
+
+	return r
+}
+
+func TestRecord(t *testing.T) {
+	cart := report.Finding{Category: report.CategoryIdleWorkload, Namespace: "shop", Kind: "Deployment",
+		Name: "cart", CPU: 500}
+	data := report.Finding{Category: report.CategoryUnusedVolume, Namespace: "shop",
+		Kind: "PersistentVolumeClaim", Name: "data", Storage: 1024 * 1024 * 1024}
+	legacy := report.Finding{Category: report.CategoryIdleWorkload, Namespace: "shop", Kind: "Deployment",
+		Name: "legacy", CPU: 250}
+
+	first := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	second := first.Add(time.Hour)
+	third := second.Add(time.Hour)
+	h := &History{}
+
+	diff := h.Record(scanReport(cart, data), first)
+	if len(diff.New) != 2 || !diff.Previous.Time.IsZero() {
+		t.Errorf("unexpected diff of the first scan: %+v", diff)
+	}
+
+	r := scanReport(cart, legacy)
+	diff = h.Record(r, second)
+	if !reflect.DeepEqual(diff.New, []string{legacy.Key()}) || !reflect.DeepEqual(diff.Resolved, []string{data.Key()}) {
+		t.Errorf("unexpected diff of the second scan: %+v", diff)
+	}
+	if diff.Current.CPU-diff.Previous.CPU != 250 {
+		t.Errorf("unexpected CPU trend: %+v", diff)
+	}
+	if !r.Findings[0].FirstSeen.Equal(first) || r.Findings[0].Streak != 2 {
+		t.Errorf("cart should be found since the first scan: %v, %v", r.Findings[0].FirstSeen, r.Findings[0].Streak)
+	}
+
+	// Streak starts again once the finding is back
+	r = scanReport(data)
+	h.Record(r, third)
+	if !r.Findings[0].FirstSeen.Equal(third) || r.Findings[0].Streak != 1 {
+		t.Errorf("data should be found since the third scan: %v, %v", r.Findings[0].FirstSeen, r.Findings[0].Streak)
+	}
+	if len(h.Scans) != 3 || len(h.Findings) != 1 {
+		t.Errorf("unexpected history: %+v", h)
+	}
+}
+
+func TestFileStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "history")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	store, err := New("file:"+filepath.Join(dir, "history.json"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h, err := store.Load()
+	if err != nil || len(h.Scans) != 0 {
+		t.Fatalf("missing file should be empty history: %+v, %v", h, err)
+	}
+
+	h.Record(scanReport(report.Finding{Category: report.CategoryStaleJob, Namespace: "ops", Kind: "Job",
+		Name: "backup"}), time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	if err := store.Save(h); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := store.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(loaded.Scans) != 1 || len(loaded.Findings) != 1 {
+		t.Errorf("unexpected loaded history: %+v", loaded)
+	}
+}
+
+func TestNew(t *testing.T) {
+	for _, spec := range []string{"file:", "configmap:ops", "configmap:/name", "redis:host"} {
+		if _, err := New(spec, nil); err == nil {
+			t.Errorf("expected error for %q", spec)
+		}
+	}
+	if store, err := New("", nil); store != nil || err != nil {
+		t.Errorf("empty spec should disable history: %v, %v", store, err)
+	}
+}
+
+func TestLimitFindings(t *testing.T) {
+	first := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	findings := map[string]*Entry{
+		"new":   {FirstSeen: first.Add(time.Hour), Streak: 1},
+		"old":   {FirstSeen: first, Streak: 2},
+		"older": {FirstSeen: first.Add(-time.Hour), Streak: 3},
+	}
+
+	limited := limitFindings(findings, 2)
+	if len(limited) != 2 || limited["old"] == nil || limited["older"] == nil {
+		t.Errorf("the longest seen findings should be kept: %v", limited)
+	}
+	if len(limitFindings(findings, MaxFindings)) != 3 {
+		t.Errorf("findings under the limit should be kept")
+	}
+}
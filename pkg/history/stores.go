@@ -0,0 +1,133 @@
+package history
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Key of the ConfigMap's data holding the history
+const configMapKey = "history.json"
+
+// FileStore keeps history in a local JSON file
+type FileStore struct {
+	path string
+}
+
+// NewFileStore returns store keeping history in the file
+func NewFileStore(path string) *FileStore {
+	return &FileStore{path: path}
+}
+
+// Load reads the file, a missing file means empty history
+func (s *FileStore) Load() (*History, error) {
+	data, err := ioutil.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return &History{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return decode(data)
+}
+
+// Save replaces the file atomically, so an interrupted save keeps the previous history
+func (s *FileStore) Save(h *History) error {
+	data, err := json.Marshal(h)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(s.path), filepath.Base(s.path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), s.path)
+}
+
+// ConfigMapStore keeps history in a ConfigMap, so it survives pod restarts and is shared by replicas
+type ConfigMapStore struct {
+	kClient   kubernetes.Interface
+	namespace string
+	name      string
+}
+
+// NewConfigMapStore returns store keeping history in the ConfigMap
+func NewConfigMapStore(kClient kubernetes.Interface, namespace, name string) *ConfigMapStore {
+	return &ConfigMapStore{kClient: kClient, namespace: namespace, name: name}
+}
+
+// Load reads the ConfigMap, a missing ConfigMap means empty history
+func (s *ConfigMapStore) Load() (*History, error) {
+	cm, err := s.kClient.CoreV1().ConfigMaps(s.namespace).Get(s.name, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return &History{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return decode([]byte(cm.Data[configMapKey]))
+}
+
+// Save creates or updates the ConfigMap
+func (s *ConfigMapStore) Save(h *History) error {
+	data, err := json.Marshal(h)
+	if err != nil {
+		return err
+	}
+
+	configMaps := s.kClient.CoreV1().ConfigMaps(s.namespace)
+	cm, err := configMaps.Get(s.name, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		_, err = configMaps.Create(&v1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      s.name,
+				Namespace: s.namespace,
+				Labels:    map[string]string{"app.kubernetes.io/managed-by": "useless-operator"},
+			},
+			Data: map[string]string{configMapKey: string(data)},
+		})
+		return err
+	}
+	if err != nil {
+		return err
+	}
+
+	if cm.Data == nil {
+		cm.Data = map[string]string{}
+	}
+	cm.Data[configMapKey] = string(data)
+	_, err = configMaps.Update(cm)
+
+	return err
+}
+
+// decode parses saved history, empty data means empty history
+func decode(data []byte) (*History, error) {
+	h := &History{}
+	if len(data) == 0 {
+		return h, nil
+	}
+	if err := json.Unmarshal(data, h); err != nil {
+		return nil, err
+	}
+
+	return h, nil
+}
@@ -0,0 +1,263 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Nastradamus/useless-operator/pkg/report"
+	"golang.org/x/time/rate"
+	"k8s.io/klog"
+)
+
+// Webhook payload formats
+const (
+	FormatJSON  = "json"
+	FormatSlack = "slack"
+	FormatTeams = "teams"
+)
+
+// Maximum findings listed in chat messages, the rest is summarized
+const maxMessageFindings = 20
+
+// Config of the notifier
+type Config struct {
+	// Webhook of teams without their own webhook, empty disables their notifications
+	URL string
+	// Webhooks by owner
+	TeamURLs map[string]string
+	Format   string
+	// Maximum webhook requests per second
+	Rate float64
+	// File keeping findings already sent, empty disables de-duplication across runs
+	StateFile string
+	Timeout   time.Duration
+}
+
+// Notifier sends each team summary of its findings to a webhook
+type Notifier struct {
+	config  Config
+	client  *http.Client
+	limiter *rate.Limiter
+}
+
+// Message is payload of the generic JSON format
+type Message struct {
+	Owner    string           `json:"owner"`
+	Summary  string           `json:"summary"`
+	CPU      int64            `json:"cpuMilli"`
+	Memory   int64            `json:"memoryBytes"`
+	Storage  int64            `json:"storageBytes"`
+	Findings []report.Finding `json:"findings"`
+}
+
+// state is findings' keys sent to each owner
+type state map[string][]string
+
+// NewNotifier validates config and returns notifier
+func NewNotifier(config Config) (*Notifier, error) {
+	switch config.Format {
+	case FormatJSON, FormatSlack, FormatTeams:
+	default:
+		return nil, fmt.Errorf("unknown webhook format %q", config.Format)
+	}
+	if config.Timeout == 0 {
+		config.Timeout = 10 * time.Second
+	}
+	limit := rate.Inf
+	if config.Rate > 0 {
+		limit = rate.Limit(config.Rate)
+	}
+
+	return &Notifier{
+		config:  config,
+		client:  &http.Client{Timeout: config.Timeout},
+		limiter: rate.NewLimiter(limit, 1),
+	}, nil
+}
+
+// Notify sends each team findings not sent by previous runs. Teams without new findings
+// and without webhook are skipped. Returns number of sent notifications.
+func (n *Notifier) Notify(r *report.Report) (int, error) {
+	sent, err := n.loadState()
+	if err != nil {
+		return 0, err
+	}
+	next := state{}
+
+	var errs []string
+	notified := 0
+	for _, team := range r.ByOwner() {
+		url := n.config.URL
+		if teamURL, ok := n.config.TeamURLs[team.Owner]; ok {
+			url = teamURL
+		}
+		if url == "" {
+			continue
+		}
+
+		known := map[string]bool{}
+		for _, key := range sent[team.Owner] {
+			known[key] = true
+		}
+		var keys []string
+		var fresh []report.Finding
+		for _, f := range team.Findings {
+			key := sentKey(&f)
+			keys = append(keys, key)
+			if !known[key] {
+				fresh = append(fresh, f)
+			}
+		}
+		if len(fresh) == 0 {
+			klog.V(3).Infof("No new findings for %v, skipping notification", team.Owner)
+			next[team.Owner] = keys
+			continue
+		}
+
+		if err := n.send(url, team, fresh); err != nil {
+			errs = append(errs, fmt.Sprintf("%v: %v", team.Owner, err))
+			// Keep previous state so new findings are retried next run
+			next[team.Owner] = sent[team.Owner]
+			continue
+		}
+		notified++
+		next[team.Owner] = keys
+	}
+
+	if err := n.saveState(next); err != nil {
+		errs = append(errs, err.Error())
+	}
+	if len(errs) > 0 {
+		return notified, fmt.Errorf("webhook notifications failed: %v", strings.Join(errs, "; "))
+	}
+
+	return notified, nil
+}
+
+// send posts team's new findings formatted for the webhook
+func (n *Notifier) send(url string, team report.TeamSummary, fresh []report.Finding) error {
+	body, err := n.payload(team, fresh)
+	if err != nil {
+		return err
+	}
+
+	if err := n.limiter.Wait(context.Background()); err != nil {
+		return err
+	}
+	resp, err := n.client.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = ioutil.ReadAll(resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded %v", resp.Status)
+	}
+	klog.V(2).Infof("Sent %v new findings to %v", len(fresh), team.Owner)
+
+	return nil
+}
+
+// payload returns request body in configured format
+func (n *Notifier) payload(team report.TeamSummary, fresh []report.Finding) ([]byte, error) {
+	summary := team.Summary()
+
+	switch n.config.Format {
+	case FormatSlack:
+		return json.Marshal(map[string]string{
+			"text": fmt.Sprintf("*%v*\n%v", summary, chatLines(fresh, "• ")),
+		})
+	case FormatTeams:
+		return json.Marshal(map[string]string{
+			"@type":    "MessageCard",
+			"@context": "https://schema.org/extensions",
+			"summary":  summary,
+			"title":    fmt.Sprintf("Useless resources of %v", team.Owner),
+			"text":     fmt.Sprintf("%v\n\n%v", summary, chatLines(fresh, "- ")),
+		})
+	}
+
+	return json.Marshal(Message{
+		Owner:    team.Owner,
+		Summary:  summary,
+		CPU:      team.CPU,
+		Memory:   team.Memory,
+		Storage:  team.Storage,
+		Findings: fresh,
+	})
+}
+
+// sentKey identifies sent finding, findings are sent again when their lifecycle state changes
+func sentKey(f *report.Finding) string {
+	if f.State != "" {
+		return f.Key() + "@" + f.State
+	}
+
+	return f.Key()
+}
+
+// chatLines returns findings one per line, truncated to maxMessageFindings
+func chatLines(findings []report.Finding, bullet string) string {
+	var lines []string
+	for i, f := range findings {
+		if i == maxMessageFindings {
+			lines = append(lines, fmt.Sprintf("%v...and %v more", bullet, len(findings)-maxMessageFindings))
+			break
+		}
+		lines = append(lines, bullet+f.String())
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// loadState reads findings sent by previous runs
+func (n *Notifier) loadState() (state, error) {
+	s := state{}
+	if n.config.StateFile == "" {
+		return s, nil
+	}
+
+	data, err := ioutil.ReadFile(n.config.StateFile)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("can't parse %v: %v", n.config.StateFile, err)
+	}
+
+	return s, nil
+}
+
+// saveState writes sent findings, those not found anymore are forgotten
+func (n *Notifier) saveState(s state) error {
+	if n.config.StateFile == "" {
+		return nil
+	}
+	for _, keys := range s {
+		sort.Strings(keys)
+	}
+
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := filepath.Join(filepath.Dir(n.config.StateFile), "."+filepath.Base(n.config.StateFile)+".tmp")
+	if err := ioutil.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, n.config.StateFile)
+}
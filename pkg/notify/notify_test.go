@@ -0,0 +1,176 @@
+package notify
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Nastradamus/useless-operator/pkg/report"
+)
+
+// stub records bodies of webhook requests by path
+type stub struct {
+	sync.Mutex
+	requests map[string][]string
+}
+
+func (s *stub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := ioutil.ReadAll(r.Body)
+	s.Lock()
+	s.requests[r.URL.Path] = append(s.requests[r.URL.Path], string(body))
+	s.Unlock()
+	if r.URL.Path == "/broken" {
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
+func finding(owner, name string) report.Finding {
+	return report.Finding{
+		Category:  report.CategoryIdleWorkload,
+		Namespace: "default",
+		Kind:      "Deployment",
+		Name:      name,
+		Reason:    "no traffic",
+		CPU:       100,
+		Owner:     owner,
+	}
+}
+
+func TestNotify(t *testing.T) {
+	s := &stub{requests: map[string][]string{}}
+	server := httptest.NewServer(s)
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "notify")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	n, err := NewNotifier(Config{
+		URL:       server.URL + "/default",
+		TeamURLs:  map[string]string{"payments": server.URL + "/payments", "search": server.URL + "/broken"},
+		Format:    FormatJSON,
+		StateFile: filepath.Join(dir, "state.json"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		findings []report.Finding
+		sent     int
+		failed   bool
+		// Number of requests received by each path so far
+		requests map[string]int
+	}{
+		{
+			name:     "first run notifies every team",
+			findings: []report.Finding{finding("payments", "api"), finding("", "web")},
+			sent:     2,
+			requests: map[string]int{"/payments": 1, "/default": 1},
+		},
+		{
+			name:     "same findings aren't sent again",
+			findings: []report.Finding{finding("payments", "api"), finding("", "web")},
+			requests: map[string]int{"/payments": 1, "/default": 1},
+		},
+		{
+			name:     "new finding is sent to its team only",
+			findings: []report.Finding{finding("payments", "api"), finding("payments", "worker"), finding("", "web")},
+			sent:     1,
+			requests: map[string]int{"/payments": 2, "/default": 1},
+		},
+		{
+			name: "failed webhook is reported",
+			findings: []report.Finding{finding("search", "indexer"), finding("payments", "api"),
+				finding("payments", "worker")},
+			failed:   true,
+			requests: map[string]int{"/payments": 2, "/default": 1, "/broken": 1},
+		},
+		{
+			name:     "failed notification is retried",
+			findings: []report.Finding{finding("search", "indexer"), finding("payments", "api")},
+			failed:   true,
+			requests: map[string]int{"/payments": 2, "/default": 1, "/broken": 2},
+		},
+		{
+			name:     "gone findings are sent again when they reappear",
+			findings: []report.Finding{finding("payments", "api"), finding("payments", "worker")},
+			sent:     1,
+			requests: map[string]int{"/payments": 3, "/default": 1, "/broken": 2},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sent, err := n.Notify(&report.Report{Findings: tt.findings})
+			if (err != nil) != tt.failed {
+				t.Errorf("error: %v, expected failure: %v", err, tt.failed)
+			}
+			if sent != tt.sent {
+				t.Errorf("sent %v notifications, expected %v", sent, tt.sent)
+			}
+			for path, count := range tt.requests {
+				if len(s.requests[path]) != count {
+					t.Errorf("%v received %v requests, expected %v", path, len(s.requests[path]), count)
+				}
+			}
+		})
+	}
+
+	var message Message
+	last := s.requests["/payments"][len(s.requests["/payments"])-1]
+	if err := json.Unmarshal([]byte(last), &message); err != nil {
+		t.Fatal(err)
+	}
+	if message.Owner != "payments" || len(message.Findings) != 1 || message.Findings[0].Name != "worker" {
+		t.Errorf("unexpected message: %+v", message)
+	}
+}
+
+func TestPayloadFormats(t *testing.T) {
+	team := report.TeamSummary{Owner: "payments", Findings: []report.Finding{finding("payments", "api")}, CPU: 100}
+
+	tests := []struct {
+		format   string
+		field    string
+		contains string
+	}{
+		{format: FormatSlack, field: "text", contains: "Deployment default/api"},
+		{format: FormatTeams, field: "title", contains: "payments"},
+		{format: FormatTeams, field: "text", contains: "Deployment default/api"},
+		{format: FormatJSON, field: "owner", contains: "payments"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format+"/"+tt.field, func(t *testing.T) {
+			n, err := NewNotifier(Config{Format: tt.format})
+			if err != nil {
+				t.Fatal(err)
+			}
+			body, err := n.payload(team, team.Findings)
+			if err != nil {
+				t.Fatal(err)
+			}
+			fields := map[string]interface{}{}
+			if err := json.Unmarshal(body, &fields); err != nil {
+				t.Fatal(err)
+			}
+			if value, _ := fields[tt.field].(string); !strings.Contains(value, tt.contains) {
+				t.Errorf("%v is %q, expected to contain %q", tt.field, value, tt.contains)
+			}
+		})
+	}
+
+	if _, err := NewNotifier(Config{Format: "xml"}); err == nil {
+		t.Error("unknown format is accepted")
+	}
+}
@@ -0,0 +1,190 @@
+package policy
+
+import (
+	"sort"
+	"time"
+
+	"github.com/Nastradamus/useless-operator/pkg/apis/useless/v1alpha1"
+	ukube "github.com/Nastradamus/useless-operator/pkg/ukubernetes"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// Policy is a valid UselessPolicy or ClusterUselessPolicy
+type Policy struct {
+	Kind string
+	// Empty for ClusterUselessPolicies
+	Namespace string
+	Name      string
+	Spec      v1alpha1.UselessPolicySpec
+
+	selector          labels.Selector
+	namespaceSelector labels.Selector
+}
+
+// Settings are effective configuration of an object, policies override defaults from command line flags
+type Settings struct {
+	// Enabled detectors, nil enables all of them
+	Detectors map[string]bool
+
+	ObservationPeriod   time.Duration
+	JobAge              time.Duration
+	CronJobSuspendedAge time.Duration
+	CronJobFailedRuns   int
+	HPAUtilization      int
+	NamespaceAge        time.Duration
+
+	Action    string
+	Lifecycle ukube.LifecycleConfig
+
+	Owner   string
+	Webhook string
+
+	// Policy the settings come from, nil for defaults
+	Policy *Policy
+}
+
+// String returns kind and name of the policy
+func (p *Policy) String() string {
+	if p.Namespace == "" {
+		return p.Kind + " " + p.Name
+	}
+
+	return p.Kind + " " + p.Namespace + "/" + p.Name
+}
+
+// Matches checks whether the policy applies to the object with labels in namespace with nsLabels
+func (p *Policy) Matches(namespace string, nsLabels, objLabels map[string]string) bool {
+	if p.Namespace != "" && p.Namespace != namespace {
+		return false
+	}
+	if p.namespaceSelector != nil && !p.namespaceSelector.Matches(labels.Set(nsLabels)) {
+		return false
+	}
+
+	return p.selector == nil || p.selector.Matches(labels.Set(objLabels))
+}
+
+// specificity ranks policies matching an object: namespaced policies win over cluster ones,
+// then policies with more selector requirements win
+func (p *Policy) specificity() int {
+	score := requirements(p.Spec.Selector) + requirements(p.Spec.NamespaceSelector)
+	if p.Namespace != "" {
+		score += 1000
+	}
+
+	return score
+}
+
+// requirements counts requirements of the selector
+func requirements(selector *metav1.LabelSelector) int {
+	if selector == nil {
+		return 0
+	}
+
+	return len(selector.MatchLabels) + len(selector.MatchExpressions)
+}
+
+// Enabled checks whether the detector is enabled
+func (s *Settings) Enabled(detector string) bool {
+	return s.Detectors == nil || s.Detectors[detector]
+}
+
+// Apply returns settings of objects the policy applies to, unset fields are kept from defaults
+func (p *Policy) Apply(defaults Settings) Settings {
+	s := defaults
+	if p == nil {
+		return s
+	}
+	s.Policy = p
+	spec := &p.Spec
+
+	if len(spec.Detectors) > 0 {
+		s.Detectors = map[string]bool{}
+		for _, detector := range spec.Detectors {
+			s.Detectors[detector] = true
+		}
+	}
+
+	setDuration(&s.ObservationPeriod, spec.ObservationPeriod)
+	setDuration(&s.JobAge, spec.Thresholds.JobAge)
+	setDuration(&s.CronJobSuspendedAge, spec.Thresholds.CronJobSuspendedAge)
+	setDuration(&s.NamespaceAge, spec.Thresholds.NamespaceAge)
+	setInt(&s.CronJobFailedRuns, spec.Thresholds.CronJobFailedRuns)
+	setInt(&s.HPAUtilization, spec.Thresholds.HPAUtilization)
+
+	if spec.Lifecycle.Action != "" {
+		s.Action = spec.Lifecycle.Action
+	}
+	setDuration(&s.Lifecycle.WarnAfter, spec.Lifecycle.WarnAfter)
+	setDuration(&s.Lifecycle.ScaleDownAfter, spec.Lifecycle.ScaleDownAfter)
+	setDuration(&s.Lifecycle.DeleteAfter, spec.Lifecycle.DeleteAfter)
+
+	if spec.Notification.Owner != "" {
+		s.Owner = spec.Notification.Owner
+		s.Webhook = spec.Notification.Webhook
+	}
+
+	return s
+}
+
+// ScanSettings returns settings detectors run with, so they find objects for every policy: the shortest ages,
+// the longest observation period, the lowest number of failed runs and the highest HPA utilization.
+// Findings are then filtered by settings of their own policies.
+func ScanSettings(policies []*Policy, defaults Settings) Settings {
+	scan := defaults
+	for _, p := range policies {
+		s := p.Apply(defaults)
+		if s.ObservationPeriod > scan.ObservationPeriod {
+			scan.ObservationPeriod = s.ObservationPeriod
+		}
+		if s.JobAge < scan.JobAge {
+			scan.JobAge = s.JobAge
+		}
+		if s.CronJobSuspendedAge < scan.CronJobSuspendedAge {
+			scan.CronJobSuspendedAge = s.CronJobSuspendedAge
+		}
+		// Zero age disables the detector
+		if s.NamespaceAge > 0 && (scan.NamespaceAge == 0 || s.NamespaceAge < scan.NamespaceAge) {
+			scan.NamespaceAge = s.NamespaceAge
+		}
+		// Zero runs disables the detector
+		if s.CronJobFailedRuns > 0 && (scan.CronJobFailedRuns == 0 || s.CronJobFailedRuns < scan.CronJobFailedRuns) {
+			scan.CronJobFailedRuns = s.CronJobFailedRuns
+		}
+		if s.HPAUtilization > scan.HPAUtilization {
+			scan.HPAUtilization = s.HPAUtilization
+		}
+	}
+
+	return scan
+}
+
+// mostSpecific returns the most specific of matching policies, ties are broken by name
+func mostSpecific(matching []*Policy) *Policy {
+	if len(matching) == 0 {
+		return nil
+	}
+	sort.Slice(matching, func(i, j int) bool {
+		if si, sj := matching[i].specificity(), matching[j].specificity(); si != sj {
+			return si > sj
+		}
+		return matching[i].String() < matching[j].String()
+	})
+
+	return matching[0]
+}
+
+// setDuration overrides value if the optional duration is set
+func setDuration(value *time.Duration, d *metav1.Duration) {
+	if d != nil {
+		*value = d.Duration
+	}
+}
+
+// setInt overrides value if the optional number is set
+func setInt(value *int, n *int32) {
+	if n != nil {
+		*value = int(*n)
+	}
+}
@@ -0,0 +1,148 @@
+package policy
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Nastradamus/useless-operator/pkg/apis/useless/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func newPolicy(kind, namespace, name string, spec v1alpha1.UselessPolicySpec) *Policy {
+	p := &Policy{Kind: kind, Namespace: namespace, Name: name, Spec: spec}
+	if spec.Selector != nil {
+		p.selector, _ = metav1.LabelSelectorAsSelector(spec.Selector)
+	}
+	if spec.NamespaceSelector != nil {
+		p.namespaceSelector, _ = metav1.LabelSelectorAsSelector(spec.NamespaceSelector)
+	}
+
+	return p
+}
+
+func TestResolve(t *testing.T) {
+	store := NewStore(nil, false)
+	for _, p := range []*Policy{
+		newPolicy(KindClusterUselessPolicy, "", "default", v1alpha1.UselessPolicySpec{}),
+		newPolicy(KindClusterUselessPolicy, "", "prod", v1alpha1.UselessPolicySpec{
+			NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"env": "prod"}},
+		}),
+		newPolicy(KindUselessPolicy, "payments", "all", v1alpha1.UselessPolicySpec{}),
+		newPolicy(KindUselessPolicy, "payments", "batch", v1alpha1.UselessPolicySpec{
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"tier": "batch"}},
+		}),
+	} {
+		store.policies[p.Kind][p.Namespace+"/"+p.Name] = p
+	}
+
+	tests := []struct {
+		name      string
+		namespace string
+		nsLabels  map[string]string
+		objLabels map[string]string
+		expected  string
+	}{
+		{
+			name:      "cluster policy without selectors applies everywhere",
+			namespace: "search",
+			expected:  "ClusterUselessPolicy default",
+		},
+		{
+			name:      "cluster policy with namespace selector wins",
+			namespace: "search",
+			nsLabels:  map[string]string{"env": "prod"},
+			expected:  "ClusterUselessPolicy prod",
+		},
+		{
+			name:      "namespaced policy wins over cluster ones",
+			namespace: "payments",
+			nsLabels:  map[string]string{"env": "prod"},
+			expected:  "UselessPolicy payments/all",
+		},
+		{
+			name:      "policy with selector wins",
+			namespace: "payments",
+			objLabels: map[string]string{"tier": "batch"},
+			expected:  "UselessPolicy payments/batch",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := store.Resolve(tt.namespace, tt.nsLabels, tt.objLabels)
+			if p == nil || p.String() != tt.expected {
+				t.Errorf("resolved %v, expected %v", p, tt.expected)
+			}
+		})
+	}
+}
+
+func TestScanSettings(t *testing.T) {
+	defaults := Settings{ObservationPeriod: 6 * time.Hour, JobAge: 7 * 24 * time.Hour, CronJobFailedRuns: 3,
+		HPAUtilization: 5}
+	utilization, runs, disabled := int32(50), int32(1), int32(0)
+	scan := ScanSettings([]*Policy{
+		newPolicy(KindClusterUselessPolicy, "", "long", v1alpha1.UselessPolicySpec{
+			ObservationPeriod: &metav1.Duration{Duration: 24 * time.Hour},
+			Thresholds:        v1alpha1.Thresholds{CronJobFailedRuns: &disabled},
+		}),
+		newPolicy(KindUselessPolicy, "batch", "jobs", v1alpha1.UselessPolicySpec{
+			Thresholds: v1alpha1.Thresholds{
+				JobAge:            &metav1.Duration{Duration: 24 * time.Hour},
+				CronJobFailedRuns: &runs,
+				HPAUtilization:    &utilization,
+			},
+		}),
+	}, defaults)
+
+	if scan.ObservationPeriod != 24*time.Hour || scan.JobAge != 24*time.Hour || scan.CronJobFailedRuns != 1 ||
+		scan.HPAUtilization != 50 || scan.NamespaceAge != 0 {
+		t.Errorf("unexpected scan settings: %+v", scan)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	negative := int32(-1)
+	p := v1alpha1.UselessPolicy{Spec: v1alpha1.UselessPolicySpec{
+		NamespaceSelector: &metav1.LabelSelector{},
+		Detectors:         []string{"idle-workload", "unknown"},
+		Thresholds:        v1alpha1.Thresholds{CronJobFailedRuns: &negative},
+		Lifecycle:         v1alpha1.Lifecycle{Action: "destroy", WarnAfter: &metav1.Duration{}},
+		Notification:      v1alpha1.Notification{Webhook: "ftp://example.com"},
+	}}
+
+	expected := []string{
+		"spec.detectors[1]",
+		"spec.thresholds.cronJobFailedRuns",
+		"spec.lifecycle.action",
+		"spec.lifecycle.warnAfter",
+		"spec.notification.webhook",
+		"spec.notification.owner",
+		"spec.namespaceSelector",
+	}
+	errs := p.Validate()
+	if len(errs) != len(expected) {
+		t.Fatalf("got %v errors, expected %v: %v", len(errs), len(expected), errs)
+	}
+	for i, err := range errs {
+		if err.Field != expected[i] {
+			t.Errorf("error %v is about %v, expected %v", i, err.Field, expected[i])
+		}
+	}
+}
+
+func TestLoadWithoutStatus(t *testing.T) {
+	// Store of commands not changing the cluster has no client writing statuses
+	store := NewStore(nil, false)
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": v1alpha1.SchemeGroupVersion.String(),
+		"kind":       KindUselessPolicy,
+		"metadata":   map[string]interface{}{"namespace": "payments", "name": "all", "generation": int64(2)},
+		"spec":       map[string]interface{}{},
+	}}
+
+	if p := store.load(KindUselessPolicy, v1alpha1.UselessPolicyResource, obj); p == nil || p.Name != "all" {
+		t.Errorf("valid policy should be loaded: %+v", p)
+	}
+}
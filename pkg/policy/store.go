@@ -0,0 +1,301 @@
+package policy
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Nastradamus/useless-operator/pkg/apis/useless/v1alpha1"
+	ukube "github.com/Nastradamus/useless-operator/pkg/ukubernetes"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/klog"
+)
+
+// Kinds of policies
+const (
+	KindUselessPolicy        = "UselessPolicy"
+	KindClusterUselessPolicy = "ClusterUselessPolicy"
+)
+
+// Delay before watching again after the watch failed
+const rewatchDelay = 10 * time.Second
+
+// Store keeps valid policies up to date
+type Store struct {
+	dClient dynamic.Interface
+	// Whether validation results are written into policies' status
+	updateStatuses bool
+
+	mu sync.RWMutex
+	// By kind and "namespace/name"
+	policies map[string]map[string]*Policy
+}
+
+// NewStore returns empty store. Commands which don't change the cluster don't update policies' status.
+func NewStore(dClient dynamic.Interface, updateStatuses bool) *Store {
+	return &Store{
+		dClient:        dClient,
+		updateStatuses: updateStatuses,
+		policies: map[string]map[string]*Policy{
+			KindUselessPolicy:        {},
+			KindClusterUselessPolicy: {},
+		},
+	}
+}
+
+// Sync lists policies of both kinds. Missing CRDs mean there are no policies.
+func (s *Store) Sync() error {
+	for kind, gvr := range policyResources() {
+		if _, err := s.list(kind, gvr); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Run watches policies of both kinds until stop is closed
+func (s *Store) Run(stop <-chan struct{}) {
+	var wg sync.WaitGroup
+	for kind, gvr := range policyResources() {
+		wg.Add(1)
+		go func(kind string, gvr schema.GroupVersionResource) {
+			defer wg.Done()
+			for {
+				if err := s.watch(kind, gvr, stop); err != nil {
+					klog.Warningf("Watching %v failed: %v", gvr.Resource, err)
+				}
+				select {
+				case <-stop:
+					return
+				case <-time.After(rewatchDelay):
+				}
+			}
+		}(kind, gvr)
+	}
+	wg.Wait()
+}
+
+// Policies returns all valid policies
+func (s *Store) Policies() []*Policy {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var policies []*Policy
+	for _, byName := range s.policies {
+		for _, p := range byName {
+			policies = append(policies, p)
+		}
+	}
+
+	return policies
+}
+
+// Resolve returns the most specific policy matching the object, nil if there is none
+func (s *Store) Resolve(namespace string, nsLabels, objLabels map[string]string) *Policy {
+	var matching []*Policy
+	for _, p := range s.Policies() {
+		if p.Matches(namespace, nsLabels, objLabels) {
+			matching = append(matching, p)
+		}
+	}
+
+	return mostSpecific(matching)
+}
+
+// list replaces policies of the kind, returns resource version to watch from
+func (s *Store) list(kind string, gvr schema.GroupVersionResource) (string, error) {
+	list, err := s.dClient.Resource(gvr).Namespace("").List(metav1.ListOptions{})
+	if errors.IsNotFound(err) {
+		klog.V(2).Infof("%v aren't served, CRD isn't installed", gvr.Resource)
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	byName := map[string]*Policy{}
+	for i := range list.Items {
+		if p := s.load(kind, gvr, &list.Items[i]); p != nil {
+			byName[p.Namespace+"/"+p.Name] = p
+		}
+	}
+
+	s.mu.Lock()
+	s.policies[kind] = byName
+	s.mu.Unlock()
+	klog.V(2).Infof("Loaded %v: %v", gvr.Resource, len(byName))
+
+	return list.GetResourceVersion(), nil
+}
+
+// watch lists policies of the kind and applies their changes until the watch ends or stop is closed
+func (s *Store) watch(kind string, gvr schema.GroupVersionResource, stop <-chan struct{}) error {
+	resourceVersion, err := s.list(kind, gvr)
+	if err != nil || resourceVersion == "" {
+		return err
+	}
+
+	w, err := s.dClient.Resource(gvr).Namespace("").Watch(metav1.ListOptions{ResourceVersion: resourceVersion})
+	if err != nil {
+		return err
+	}
+	defer w.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return nil
+		case event, ok := <-w.ResultChan():
+			if !ok {
+				return nil
+			}
+			obj, ok := event.Object.(*unstructured.Unstructured)
+			if !ok {
+				// Watch error, e.g. expired resource version
+				return fmt.Errorf("unexpected watch event %v: %v", event.Type, event.Object)
+			}
+			key := obj.GetNamespace() + "/" + obj.GetName()
+
+			s.mu.Lock()
+			delete(s.policies[kind], key)
+			s.mu.Unlock()
+			if event.Type == watch.Deleted {
+				klog.V(2).Infof("%v %v deleted", kind, key)
+				continue
+			}
+			if p := s.load(kind, gvr, obj); p != nil {
+				s.mu.Lock()
+				s.policies[kind][key] = p
+				s.mu.Unlock()
+				klog.V(2).Infof("%v applied", p)
+			}
+		}
+	}
+}
+
+// load converts and validates the policy, reporting the result in its status if statuses are updated.
+// Returns nil for invalid policies.
+func (s *Store) load(kind string, gvr schema.GroupVersionResource, obj *unstructured.Unstructured) *Policy {
+	var (
+		meta   metav1.ObjectMeta
+		spec   v1alpha1.UselessPolicySpec
+		status v1alpha1.UselessPolicyStatus
+		errs   []error
+	)
+	if kind == KindUselessPolicy {
+		var policy v1alpha1.UselessPolicy
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, &policy); err != nil {
+			errs = append(errs, err)
+		} else {
+			errs = append(errs, policy.Validate().ToAggregate())
+		}
+		meta, spec, status = policy.ObjectMeta, policy.Spec, policy.Status
+	} else {
+		var policy v1alpha1.ClusterUselessPolicy
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, &policy); err != nil {
+			errs = append(errs, err)
+		} else {
+			errs = append(errs, policy.Validate().ToAggregate())
+		}
+		meta, spec, status = policy.ObjectMeta, policy.Spec, policy.Status
+	}
+
+	var invalid error
+	for _, err := range errs {
+		if err != nil {
+			invalid = err
+		}
+	}
+	if invalid != nil {
+		klog.Warningf("Ignoring invalid %v %v/%v: %v", kind, obj.GetNamespace(), obj.GetName(), invalid)
+	}
+	if s.updateStatuses {
+		s.updateStatus(gvr, obj, meta.Generation, status, invalid)
+	}
+	if invalid != nil {
+		return nil
+	}
+
+	p := &Policy{Kind: kind, Namespace: meta.Namespace, Name: meta.Name, Spec: spec}
+	if spec.Selector != nil {
+		p.selector, _ = metav1.LabelSelectorAsSelector(spec.Selector)
+	}
+	if spec.NamespaceSelector != nil {
+		p.namespaceSelector, _ = metav1.LabelSelectorAsSelector(spec.NamespaceSelector)
+	}
+
+	return p
+}
+
+// updateStatus reports validation result of the policy's generation, failures only affect kubectl output
+func (s *Store) updateStatus(gvr schema.GroupVersionResource, obj *unstructured.Unstructured, generation int64,
+	old v1alpha1.UselessPolicyStatus, invalid error) {
+
+	status := v1alpha1.UselessPolicyStatus{ObservedGeneration: generation, Valid: invalid == nil}
+	if invalid != nil {
+		status.Message = invalid.Error()
+	}
+	if status == old {
+		return
+	}
+
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&status)
+	if err == nil {
+		obj = obj.DeepCopy()
+		err = unstructured.SetNestedField(obj.Object, content, "status")
+	}
+	if err == nil {
+		_, err = s.dClient.Resource(gvr).Namespace(obj.GetNamespace()).UpdateStatus(obj, metav1.UpdateOptions{})
+	}
+	if err != nil {
+		klog.V(4).Infof("Can't update status of %v %v/%v: %v", gvr.Resource, obj.GetNamespace(), obj.GetName(), err)
+	}
+}
+
+// policyResources returns resources of policies by kind
+func policyResources() map[string]schema.GroupVersionResource {
+	return map[string]schema.GroupVersionResource{
+		KindUselessPolicy:        v1alpha1.UselessPolicyResource,
+		KindClusterUselessPolicy: v1alpha1.ClusterUselessPolicyResource,
+	}
+}
+
+// Resolver finds settings of objects
+type Resolver struct {
+	store    *Store
+	metadata *ukube.MetadataCache
+	defaults Settings
+}
+
+// NewResolver returns resolver applying policies from the store on defaults
+func NewResolver(store *Store, metadata *ukube.MetadataCache, defaults Settings) *Resolver {
+	return &Resolver{store: store, metadata: metadata, defaults: defaults}
+}
+
+// Settings returns settings of the object, empty namespace means cluster-scoped object.
+// Objects which can't be read get defaults.
+func (r *Resolver) Settings(namespace, kind, name string) Settings {
+	var nsLabels, objLabels map[string]string
+	if meta, err := r.metadata.Get(namespace, kind, name); err == nil {
+		objLabels = meta.Labels
+	} else {
+		klog.V(4).Infof("%v (resource may disappear)", err)
+	}
+	if kind == "Namespace" {
+		// Policies in the namespace apply to the namespace itself
+		namespace, nsLabels = name, objLabels
+	} else if namespace != "" {
+		if meta, err := r.metadata.Get("", "Namespace", namespace); err == nil {
+			nsLabels = meta.Labels
+		}
+	}
+
+	return r.store.Resolve(namespace, nsLabels, objLabels).Apply(r.defaults)
+}
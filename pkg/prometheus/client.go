@@ -0,0 +1,171 @@
+package prometheus
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/api"
+	v1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
+)
+
+// Timeout of a query unless configured
+const DefaultTimeout = 60 * time.Second
+
+// ClientConfig configures authentication, TLS and timeouts of Prometheus API requests, e.g. to reach Thanos
+// Querier behind an authenticating proxy or a Cortex/Mimir tenant
+type ClientConfig struct {
+	BearerToken string
+	// Read on every request, so rotated tokens (e.g. of service accounts) are picked up
+	BearerTokenFile string
+	Username        string
+	Password        string
+	PasswordFile    string
+
+	// CA bundle verifying the server, system roots by default
+	CAFile string
+	// Client certificate and key
+	CertFile           string
+	KeyFile            string
+	InsecureSkipVerify bool
+
+	// Headers added to every request, e.g. X-Scope-OrgID
+	Headers map[string]string
+	// Proxy of requests, $HTTPS_PROXY, $HTTP_PROXY and $NO_PROXY by default
+	ProxyURL string
+	// Timeout of a query, DefaultTimeout if zero
+	Timeout time.Duration
+}
+
+// Client queries Prometheus API
+type Client struct {
+	api     v1.API
+	timeout time.Duration
+}
+
+// NewClient returns client of Prometheus at address, certificates and password file are read once
+func NewClient(address string, config ClientConfig) (*Client, error) {
+	if config.BearerToken != "" && config.BearerTokenFile != "" {
+		return nil, fmt.Errorf("bearer token and bearer token file can't be used together")
+	}
+	if (config.BearerToken != "" || config.BearerTokenFile != "") && config.Username != "" {
+		return nil, fmt.Errorf("bearer token and basic auth can't be used together")
+	}
+	if config.Password != "" && config.PasswordFile != "" {
+		return nil, fmt.Errorf("password and password file can't be used together")
+	}
+	if (config.CertFile == "") != (config.KeyFile == "") {
+		return nil, fmt.Errorf("client certificate and key must be used together")
+	}
+
+	tlsConfig := &tls.Config{InsecureSkipVerify: config.InsecureSkipVerify}
+	if config.CAFile != "" {
+		ca, err := ioutil.ReadFile(config.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("no certificates in CA file %v", config.CAFile)
+		}
+	}
+	if config.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(config.CertFile, config.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	if config.ProxyURL != "" {
+		proxy, err := url.Parse(config.ProxyURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy URL: %v", err)
+		}
+		transport.Proxy = http.ProxyURL(proxy)
+	}
+
+	password := config.Password
+	if config.PasswordFile != "" {
+		data, err := ioutil.ReadFile(config.PasswordFile)
+		if err != nil {
+			return nil, err
+		}
+		password = strings.TrimSpace(string(data))
+	}
+
+	client, err := api.NewClient(api.Config{
+		Address: address,
+		RoundTripper: &authRoundTripper{
+			next:            transport,
+			bearerToken:     config.BearerToken,
+			bearerTokenFile: config.BearerTokenFile,
+			username:        config.Username,
+			password:        password,
+			headers:         config.Headers,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	timeout := config.Timeout
+	if timeout == 0 {
+		timeout = DefaultTimeout
+	}
+
+	return &Client{api: v1.NewAPI(client), timeout: timeout}, nil
+}
+
+// query runs instant query, each query gets its own timeout
+func (c *Client) query(promQuery string, ts time.Time) (model.Value, v1.Warnings, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	// Release the timer of the context, the connection is reused by the next query
+	defer cancel()
+
+	return c.api.Query(ctx, promQuery, ts)
+}
+
+// authRoundTripper adds credentials and headers to requests
+type authRoundTripper struct {
+	next            http.RoundTripper
+	bearerToken     string
+	bearerTokenFile string
+	username        string
+	password        string
+	headers         map[string]string
+}
+
+// RoundTrip sends a copy of the request with credentials and headers, the request itself isn't modified
+func (rt *authRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	for name, value := range rt.headers {
+		req.Header.Set(name, value)
+	}
+
+	token := rt.bearerToken
+	if rt.bearerTokenFile != "" {
+		data, err := ioutil.ReadFile(rt.bearerTokenFile)
+		if err != nil {
+			return nil, fmt.Errorf("can't read bearer token: %v", err)
+		}
+		token = strings.TrimSpace(string(data))
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	if rt.username != "" {
+		req.SetBasicAuth(rt.username, rt.password)
+	}
+
+	return rt.next.RoundTrip(req)
+}
@@ -0,0 +1,94 @@
+package prometheus
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Response of a query without samples
+const emptyVector = `{"status":"success","data":{"resultType":"vector","result":[]}}`
+
+func TestClientAuth(t *testing.T) {
+	dir, err := ioutil.TempDir("", "prometheus")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	tokenFile := filepath.Join(dir, "token")
+
+	var requests []*http.Request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r)
+		_, _ = w.Write([]byte(emptyVector))
+	}))
+	defer server.Close()
+
+	client, err := NewClient(server.URL, ClientConfig{BearerTokenFile: tokenFile,
+		Headers: map[string]string{"X-Scope-OrgID": "team-a"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Rotated tokens are picked up by the next request
+	for _, token := range []string{"first", "second"} {
+		if err := ioutil.WriteFile(tokenFile, []byte(token+"\n"), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := SeriesExist(client, "up", ""); err != nil {
+			t.Fatal(err)
+		}
+		r := requests[len(requests)-1]
+		if r.Header.Get("Authorization") != "Bearer "+token || r.Header.Get("X-Scope-OrgID") != "team-a" {
+			t.Errorf("unexpected headers: %v", r.Header)
+		}
+	}
+
+	client, err = NewClient(server.URL, ClientConfig{Username: "scanner", Password: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := SeriesExist(client, "up", ""); err != nil {
+		t.Fatal(err)
+	}
+	if username, password, ok := requests[len(requests)-1].BasicAuth(); !ok || username != "scanner" ||
+		password != "secret" {
+		t.Errorf("unexpected basic auth: %v, %v", username, password)
+	}
+}
+
+func TestClientTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	}))
+	defer server.Close()
+
+	client, err := NewClient(server.URL, ClientConfig{Timeout: 10 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := SeriesExist(client, "up", ""); err == nil || !strings.Contains(err.Error(), "deadline") {
+		t.Errorf("query should time out, got %v", err)
+	}
+}
+
+func TestClientConfig(t *testing.T) {
+	for _, config := range []ClientConfig{
+		{BearerToken: "token", BearerTokenFile: "token"},
+		{BearerToken: "token", Username: "scanner"},
+		{CertFile: "client.crt"},
+		{CAFile: "missing.crt"},
+	} {
+		if _, err := NewClient("http://prometheus:9090", config); err == nil {
+			t.Errorf("config %+v should be rejected", config)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package prometheus
+
+import (
+	"fmt"
+
+	"github.com/prometheus/common/model"
+	"k8s.io/klog"
+)
+
+// Highest CPU utilization during the last hour of HPAs (kube-state-metrics) which didn't scale above minReplicas,
+// if it stayed below the threshold (percent)
+const promQueryPinnedHPAs = `max_over_time(kube_horizontalpodautoscaler_status_target_metric` +
+	`{metric_name="cpu", metric_target_type="utilization"}[1h]) < %v ` +
+	`and on (namespace, horizontalpodautoscaler) ` +
+	`max_over_time(kube_horizontalpodautoscaler_status_current_replicas[1h]) ` +
+	`<= on (namespace, horizontalpodautoscaler) kube_horizontalpodautoscaler_spec_min_replicas`
+
+// GetPinnedHPAs returns HorizontalPodAutoscalers (Element) pinned at minReplicas with CPU utilization below
+// utilization percent during the whole observed period and their highest utilization, with real observed period
+// in hours
+func GetPinnedHPAs(client *Client, maxSteps int, utilization int) (map[Namespace]map[Element]float64, int, error) {
+	promQuery := fmt.Sprintf(promQueryPinnedHPAs, utilization)
+	klog.V(4).Infof("HPAs query: %v", promQuery)
+
+	var resultMap = map[Namespace]map[Element]float64{}
+	observedPeriod, err := QueryVectorSteps(client, maxSteps, promQuery, func(step int, vector model.Vector) {
+		// Temporary map for current step
+		var tempMap = map[Namespace]map[Element]float64{}
+		for _, sample := range vector {
+			ns, hpa := Namespace(sample.Metric["namespace"]), Element(sample.Metric["horizontalpodautoscaler"])
+			if tempMap[ns] == nil {
+				tempMap[ns] = map[Element]float64{}
+			}
+			tempMap[ns][hpa] = float64(sample.Value)
+		}
+
+		if step == 0 {
+			resultMap = tempMap
+			return
+		}
+
+		// If HPA scaled up or utilization grew on any step, consider it as "useful"
+		for ns, hpas := range resultMap {
+			for hpa, peak := range hpas {
+				current, ok := tempMap[ns][hpa]
+				switch {
+				case !ok:
+					delete(hpas, hpa)
+				case current > peak:
+					hpas[hpa] = current
+				}
+			}
+			if len(hpas) == 0 {
+				delete(resultMap, ns)
+			}
+		}
+	})
+	if err != nil {
+		return map[Namespace]map[Element]float64{}, 0, err
+	}
+
+	return resultMap, observedPeriod, nil
+}
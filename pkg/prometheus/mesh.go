@@ -0,0 +1,148 @@
+package prometheus
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/prometheus/common/model"
+	"k8s.io/klog"
+)
+
+// MeshWorkload is a destination workload of service mesh requests. Kind is empty if the mesh doesn't report it.
+type MeshWorkload struct {
+	Namespace string
+	Kind      string
+	Name      string
+}
+
+// MeshProvider describes application request metrics of a service mesh, keyed by destination workload.
+// Unlike packet counters they don't count sidecars' own telemetry and health checks.
+type MeshProvider struct {
+	Name string
+	// Requests counter
+	Metric string
+	// Additional label matchers, e.g. `reporter="destination"`
+	Selector string
+	// Labels to aggregate by
+	Labels []string
+	// Key maps labels of a series to destination workload, false if series can't be mapped
+	Key func(metric model.Metric) (MeshWorkload, bool)
+}
+
+// MeshProviders are known service meshes in order of auto-detection
+var MeshProviders = []*MeshProvider{
+	{
+		// https://istio.io/latest/docs/reference/config/metrics/
+		Name:     "istio",
+		Metric:   "istio_requests_total",
+		Selector: `reporter="destination",destination_workload!="",destination_workload!="unknown"`,
+		Labels:   []string{"destination_workload_namespace", "destination_workload"},
+		Key: func(m model.Metric) (MeshWorkload, bool) {
+			return MeshWorkload{
+				Namespace: string(m["destination_workload_namespace"]),
+				Name:      string(m["destination_workload"]),
+			}, m["destination_workload_namespace"] != ""
+		},
+	},
+	{
+		// https://linkerd.io/2/reference/proxy-metrics/
+		Name:     "linkerd",
+		Metric:   "request_total",
+		Selector: `direction="inbound"`,
+		Labels:   []string{"namespace", "deployment", "statefulset", "daemonset"},
+		Key: func(m model.Metric) (MeshWorkload, bool) {
+			w := MeshWorkload{Namespace: string(m["namespace"])}
+			switch {
+			case m["deployment"] != "":
+				w.Kind, w.Name = "Deployment", string(m["deployment"])
+			case m["statefulset"] != "":
+				w.Kind, w.Name = "StatefulSet", string(m["statefulset"])
+			case m["daemonset"] != "":
+				w.Kind, w.Name = "DaemonSet", string(m["daemonset"])
+			default:
+				return w, false
+			}
+			return w, w.Namespace != ""
+		},
+	},
+}
+
+// UnusedQuery returns query of destination workloads without requests during the last hour
+func (p *MeshProvider) UnusedQuery() string {
+	return fmt.Sprintf(`sum(rate(%v{%v}[1h])) by (%v) == 0`, p.Metric, p.Selector, strings.Join(p.Labels, ", "))
+}
+
+// GetMeshProviders returns providers by names
+func GetMeshProviders(names []string) ([]*MeshProvider, error) {
+	var providers []*MeshProvider
+	for _, name := range names {
+		found := false
+		for _, p := range MeshProviders {
+			if p.Name == name {
+				providers = append(providers, p)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown mesh provider: %v", name)
+		}
+	}
+
+	return providers, nil
+}
+
+// DetectMeshProviders returns providers whose metrics are present in Prometheus
+func DetectMeshProviders(client *Client) ([]*MeshProvider, error) {
+	var providers []*MeshProvider
+	for _, p := range MeshProviders {
+		found, err := SeriesExist(client, p.Metric, p.Selector)
+		if err != nil {
+			return nil, err
+		}
+		if found {
+			klog.V(3).Infof("Detected mesh provider: %v", p.Name)
+			providers = append(providers, p)
+		}
+	}
+
+	return providers, nil
+}
+
+// GetUnusedMeshWorkloads returns destination workloads without application requests during the whole
+// observed period with real observed period in hours
+func GetUnusedMeshWorkloads(client *Client, maxSteps int, provider *MeshProvider) (map[MeshWorkload]bool, int, error) {
+	promQuery := provider.UnusedQuery()
+	klog.V(4).Infof("Mesh provider %v query: %v", provider.Name, promQuery)
+
+	var resultMap map[MeshWorkload]bool
+	observedPeriod, err := QueryVectorSteps(client, maxSteps, promQuery, func(step int, vector model.Vector) {
+		// Temporary map for current step
+		tempMap := map[MeshWorkload]bool{}
+		for _, sample := range vector {
+			w, ok := provider.Key(sample.Metric)
+			if !ok {
+				klog.V(8).Infof("Skipping series %v of mesh provider %v", sample.Metric, provider.Name)
+				continue
+			}
+			tempMap[w] = true
+		}
+
+		if step == 0 {
+			resultMap = tempMap
+			return
+		}
+
+		// If we see requests on any step, consider the workload as "useful"
+		for w := range resultMap {
+			if !tempMap[w] {
+				delete(resultMap, w)
+			}
+		}
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return resultMap, observedPeriod, nil
+}
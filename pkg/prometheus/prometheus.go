@@ -1,14 +1,13 @@
 package prometheus
 
 import (
-	"context"
+	"fmt"
 	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/klog"
 	"strings"
 	"time"
 
-	"github.com/prometheus/client_golang/api"
-	v1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
 )
 
 type Namespace string
@@ -29,8 +28,22 @@ type IngressBackend struct {
 	ServicePort intstr.IntOrString `json:"servicePort" protobuf:"bytes,2,opt,name=servicePort"`
 }
 
+// IngressKey identifies unused traffic entry. Controllers reporting per-ingress metrics fill Ingress
+// (and Host/Path if they have them), Gateway API implementations fill Route, controllers reporting
+// per-upstream metrics fill Service instead.
+type IngressKey struct {
+	Namespace IngNamespace
+	Ingress   Ingress
+	Host      Host
+	Path      Path
+	Service   string
+	RouteKind string // HTTPRoute or GRPCRoute
+	Route     string
+	RouteRule string // rule index, empty means the whole route
+}
+
 type IngressMap struct {
-	M map[IngNamespace]map[Ingress]map[Host]map[Path]IngressBackend
+	M map[IngressKey]IngressBackend
 }
 
 // END OF GetUnusedIngresses structures
@@ -38,37 +51,57 @@ type IngressMap struct {
 // AddIntoIngMap is a method for IngMapInterface
 // Fills maps from Prometheus. Example of metric:
 // {exported_namespace="polo",host="polo-stage.test.com",ingress="polo-api-staging-p8080-1496620443",path="/"}
-func (resultMap *IngressMap) AddIntoIngMap(ns IngNamespace, ing Ingress, host Host, path Path) {
-	// implements insert into `map[IngNamespace]map[Ingress]map[Host]map[Path]IngressBackend`
-
+func (resultMap *IngressMap) AddIntoIngMap(key IngressKey) {
 	if resultMap.M == nil {
-		resultMap.M = make(map[IngNamespace]map[Ingress]map[Host]map[Path]IngressBackend)
+		resultMap.M = make(map[IngressKey]IngressBackend)
+	}
+	if _, ok := resultMap.M[key]; ok {
+		return
 	}
 
-	// Try to read map[IngNamespace]
-	nsMap, ok := resultMap.M[ns]
-	if !ok {
-		// Try to read outer map
-		nsMap = make(map[Ingress]map[Host]map[Path]IngressBackend)
-		resultMap.M[ns] = nsMap
-		// Try to read deeper map `map[Ingress]map[Host]map[Path]IngressBackend`
-		iMap, ok := resultMap.M[ns][ing]
-		if !ok {
-			iMap = make(map[Host]map[Path]IngressBackend)
-			resultMap.M[ns][ing] = iMap
-			// Try to read deeper map `map[Host]map[Path]IngressBackend`
-			hMap, ok := resultMap.M[ns][ing][host]
-			if !ok {
-				hMap = make(map[Path]IngressBackend)
-				resultMap.M[ns][ing][host] = hMap
-				// Fill backend structure with empty values
-				empt := intstr.IntOrString{}
-				resultMap.M[ns][ing][host][path] = IngressBackend{"", empt}
-			}
+	// Services are known backends already
+	resultMap.M[key] = IngressBackend{ServiceName: key.Service}
+}
+
+// Merge adds entries of another map
+func (resultMap *IngressMap) Merge(other IngressMap) {
+	for key := range other.M {
+		resultMap.AddIntoIngMap(key)
+	}
+}
+
+// Intersect deletes entries which another map of the same kind of keys doesn't have
+func (resultMap *IngressMap) Intersect(other IngressMap) {
+	for key := range resultMap.M {
+		if _, ok := other.M[key]; !ok {
+			delete(resultMap.M, key)
 		}
 	}
 }
 
+// CombineIngressMaps returns entries found by providers (maps[i] is found by providers[i]). Entries of providers
+// with the same kind of keys are idle only if idle for all of them, as traffic served by any controller makes an
+// entry busy. Entries of other kinds can't be compared and are merged.
+func CombineIngressMaps(providers []*IngressProvider, maps []IngressMap) IngressMap {
+	byKind := map[string]*IngressMap{}
+	for i, provider := range providers {
+		if m, ok := byKind[provider.KeyKind]; ok {
+			m.Intersect(maps[i])
+			continue
+		}
+		m := IngressMap{}
+		m.Merge(maps[i])
+		byKind[provider.KeyKind] = &m
+	}
+
+	combined := IngressMap{}
+	for _, m := range byKind {
+		combined.Merge(*m)
+	}
+
+	return combined
+}
+
 // GetLabelVal returns given label's value from Prometheus string. Exmaple of string:
 // {exported_namespace="polo",host="polo-stage.test.com",ingress="polo-api-staging-p8080-1496620443",path="/"}
 func GetLabelVal(str *string, label string) string {
@@ -98,41 +131,41 @@ func MapAdd(m map[Namespace]map[Element]string, ns Namespace, elem Element, depl
 	mm[elem] = deployment
 }
 
+// intersectMaps deletes from resultMap elements which don't exist in tempMap, and empty namespaces
+func intersectMaps(resultMap, tempMap map[Namespace]map[Element]string) {
+	for ns, elements := range resultMap {
+		for elem := range elements {
+			if _, ok := tempMap[ns][elem]; !ok {
+				delete(elements, elem)
+			}
+		}
+		if len(elements) == 0 {
+			delete(resultMap, ns)
+		}
+	}
+}
+
 // GetUnusedResources returns map of unused resources with real observed period in hours.
 // This function works only for metrics with two elements. Example:
 // `sum(rate(nginx_ingress_controller_requests[1h])) by (ingress, exported_namespace) == 0`
-func GetUnusedResources(promAddr string, maxSteps int, promQuery string) (map[Namespace]map[Element]string, int, error) {
+func GetUnusedResources(client *Client, maxSteps int, promQuery string) (map[Namespace]map[Element]string, int, error) {
 
 	// Resulting map to return
 	var resultMap = map[Namespace]map[Element]string{}
 
-	// Setup Prometheus client
-	client, err := api.NewClient(api.Config{
-		Address: promAddr,
-	})
-	if err != nil {
-		return map[Namespace]map[Element]string{}, 0, err
-	}
-	v1api := v1.NewAPI(client)
-
 	observedPeriod := 0
 	// Query Prometheus with 1 hour shift backwards
 	for step := 0; step < maxSteps; step++ {
 		startTime := time.Now().Add(-1 * time.Duration(step) * time.Hour)
 
-		// Setup connection to Prometheus
-		ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
-
 		// Query Prometheus (opens connection)
-		result, warnings, err := v1api.Query(ctx, promQuery, startTime)
+		result, warnings, err := client.query(promQuery, startTime)
 		if err != nil {
 			return map[Namespace]map[Element]string{}, 0, err
 		}
 		if len(warnings) > 0 {
 			klog.Warningf("Warnings: %v\n", warnings)
 		}
-		// Close current connection due to free memory on the Prometheus instance after each query
-		cancel()
 
 		// Split results to strings
 		strs := strings.Split(result.String(), "\n")
@@ -173,140 +206,95 @@ func GetUnusedResources(promAddr string, maxSteps int, promQuery string) (map[Na
 
 		if step == 0 {
 			resultMap = tempMap
+			continue
 		}
 
-		// Delete from resultMap values which are not exists in tempMap
-		// TODO: understand why it's slow here in debug log mode (buffered i/o while logging?)
-		for ns, resMap := range resultMap {
-			if step == 0 {
-				break
-			}
-			for res := range resMap {
-				// Try to find element in current tempMap
-				_, ok := tempMap[ns][res]
-				if !ok {
-					// If we see non-empty result on any step, consider this resource as "useful"
-					delete(resultMap[ns], res)
-					klog.V(8).Infof("Dleteted from resultMap: ns: %v, res %v\n", ns, res)
-				}
-			}
-		}
-		// TODO: delete empty namespaces. This is a bug!
+		// If we see non-empty result on any step, consider this resource as "useful"
+		intersectMaps(resultMap, tempMap)
 	}
 
 	return resultMap, observedPeriod, nil
 }
 
-// GetUnusedIngresses
+// GetUnusedIngresses fills the map with ingresses (or upstream services) without traffic during the whole
+// observed period according to provider's metrics, e.g. for ingress-nginx:
 // `sum(rate(nginx_ingress_controller_request_size_count[1h])) by (exported_namespace, ingress, host, path) == 0`
-func (resultMap *IngressMap) GetUnusedIngresses(promAddr string, maxSteps int, promQuery string) (observedPeriod int, err error) {
+func (resultMap *IngressMap) GetUnusedIngresses(client *Client, maxSteps int, provider *IngressProvider) (observedPeriod int, err error) {
+	promQuery := provider.UnusedQuery()
+	klog.V(4).Infof("Ingress provider %v query: %v", provider.Name, promQuery)
+
+	return QueryVectorSteps(client, maxSteps, promQuery, func(step int, vector model.Vector) {
+		// Temporary map for current step
+		var tempMap IngressMap
+
+		for _, sample := range vector {
+			key, ok := provider.Key(sample.Metric)
+			if !ok {
+				klog.V(8).Infof("Skipping series %v of ingress provider %v", sample.Metric, provider.Name)
+				continue
+			}
+			tempMap.AddIntoIngMap(key)
+		}
 
-	// Setup Prometheus client
-	client, err := api.NewClient(api.Config{
-		Address: promAddr,
+		// Fill empty result map
+		if step == 0 || resultMap.M == nil {
+			*resultMap = tempMap
+			return
+		}
+
+		// Delete from resultMap values which are not exists in tempMap
+		for key := range resultMap.M {
+			if _, ok := tempMap.M[key]; !ok {
+				// If we see non-empty result on any step, consider this resource as "useful"
+				delete(resultMap.M, key)
+			}
+		}
 	})
-	if err != nil {
-		return 0, err
-	}
-	v1api := v1.NewAPI(client)
+}
+
+// QueryVectorSteps runs instant query for every hour of the observation period going backwards and calls
+// visit with each non-empty result. Stops when there is no data anymore. Returns observed period in hours.
+func QueryVectorSteps(client *Client, maxSteps int, promQuery string,
+	visit func(step int, vector model.Vector)) (observedPeriod int, err error) {
 
-	observedPeriod = 0
 	// Query Prometheus with 1 hour shift backwards
 	for step := 0; step < maxSteps; step++ {
 		startTime := time.Now().Add(-1 * time.Duration(step) * time.Hour)
 
-		// Setup connection to Prometheus
-		ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
-
 		// Query Prometheus (opens connection)
-		result, warnings, err := v1api.Query(ctx, promQuery, startTime)
+		result, warnings, err := client.query(promQuery, startTime)
 		if err != nil {
 			return 0, err
 		}
 		if len(warnings) > 0 {
 			klog.Warningf("Warnings: %v\n", warnings)
 		}
-		// Close current connection due to free memory on the Prometheus instance after each query
-		cancel()
 
-		// Split results to strings
-		strs := strings.Split(result.String(), "\n")
+		vector, ok := result.(model.Vector)
+		if !ok {
+			return 0, fmt.Errorf("unexpected result type of query %v: %v", promQuery, result.Type())
+		}
 
 		observedPeriod = step + 1 // step by 1 hour
-		// Don't read empty strings (1 is empty array of strings)
-		if len(strs) < 2 {
+		// No data (anymore)
+		if len(vector) == 0 {
 			break
 		}
 
-		// Temporary map for current step
-		var tempMap IngressMap
-
-		// Parse strings and add to map
-		for _, str := range strs {
-			// Cut part without values
-			cut := strings.Split(str, " => ")
-
-			// Don't panic if no result
-			if cut[0] == "{}" {
-				continue
-			}
-
-			// Extract values from query result
-			// {exported_namespace="polo",host="polo-stage.test.com",ingress="polo-api-staging-p8080-1496620443",path="/"}
-
-			namespace := GetLabelVal(&str, "exported_namespace")
-			ingress := GetLabelVal(&str, "ingress")
-			host := GetLabelVal(&str, "host")
-			path := GetLabelVal(&str, "path")
-			// TODO: validate len of each value and skip failed strings (now work-arounded in the Prometheus query)
-
-			tempMap.AddIntoIngMap(IngNamespace(namespace), Ingress(ingress), Host(host), Path(path))
-		}
-
-		// Fill empty result map
-		if step == 0 || resultMap.M == nil {
-			*resultMap = tempMap
-		}
-
-		// Delete from resultMap values which are not exists in tempMap
-		for ns, ingMap := range resultMap.M {
-			if step == 0 {
-				break
-			}
+		visit(step, vector)
+	}
 
-			// Delete namespace from resultMap if not found in any iteration of cycle
-			_, ok := tempMap.M[ns]
-			if !ok {
-				resultMap.M[ns] = nil
-				continue
-			}
+	return observedPeriod, nil
+}
 
-			for ing, hostMap := range ingMap {
-				// Delete ingress from resultMap if not found in any iteration of cycle
-				_, ok := tempMap.M[ns][ing]
-				if !ok {
-					delete(resultMap.M[ns], ing)
-					continue
-				}
-				for host, pathMap := range hostMap {
-					// Delete host from resultMap if not found in any iteration of cycle
-					_, ok := tempMap.M[ns][ing]
-					if !ok {
-						delete(resultMap.M[ns][ing], host)
-						continue
-					}
-					for path := range pathMap {
-						// Delete path from resultMap if not found in any iteration of cycle
-						_, ok := tempMap.M[ns][ing][host][path]
-						if !ok {
-							delete(resultMap.M[ns][ing][host], path)
-							continue
-						}
-					}
-				}
-			}
-		}
+// SeriesExist checks whether Prometheus has series of metric matching selector now
+func SeriesExist(client *Client, metric, selector string) (bool, error) {
+	result, _, err := client.query(fmt.Sprintf(`count(%v{%v})`, metric, selector), time.Now())
+	if err != nil {
+		return false, err
 	}
 
-	return observedPeriod, nil
-}
\ No newline at end of file
+	vector, ok := result.(model.Vector)
+
+	return ok && len(vector) > 0, nil
+}
@@ -0,0 +1,66 @@
+package prometheus
+
+import (
+	"testing"
+
+	"github.com/prometheus/common/model"
+)
+
+// providerKey returns key of a series of the provider
+func providerKey(t *testing.T, name string, metric model.Metric) IngressKey {
+	providers, err := GetIngressProviders([]string{name})
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, ok := providers[0].Key(metric)
+	if !ok {
+		t.Fatalf("%v can't map %v", name, metric)
+	}
+
+	return key
+}
+
+func TestCombineIngressMaps(t *testing.T) {
+	providers, err := GetIngressProviders([]string{"nginx", "haproxy", "istio", "envoy-gateway"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	nginxAPI := providerKey(t, "nginx", model.Metric{"exported_namespace": "shop", "ingress": "shop",
+		"host": "shop.example.com", "path": "/api"})
+	haproxyCart := providerKey(t, "haproxy", model.Metric{"proxy": "shop_cart_8080"})
+	haproxyLegacy := providerKey(t, "haproxy", model.Metric{"proxy": "shop_legacy_8080"})
+	istioCart := providerKey(t, "istio", model.Metric{"destination_service_namespace": "shop",
+		"destination_service_name": "cart"})
+	gatewayRoute := providerKey(t, "envoy-gateway", model.Metric{"envoy_cluster_name": "httproute/shop/web/rule/0"})
+
+	maps := make([]IngressMap, len(providers))
+	maps[0].AddIntoIngMap(nginxAPI)
+	maps[1].AddIntoIngMap(haproxyCart)
+	// Requests to legacy go through Istio ingress gateway
+	maps[1].AddIntoIngMap(haproxyLegacy)
+	maps[2].AddIntoIngMap(istioCart)
+	maps[3].AddIntoIngMap(gatewayRoute)
+
+	combined := CombineIngressMaps(providers, maps)
+	for _, key := range []IngressKey{nginxAPI, haproxyCart, gatewayRoute} {
+		if _, ok := combined.M[key]; !ok {
+			t.Errorf("%+v should be idle: %v", key, combined.M)
+		}
+	}
+	if _, ok := combined.M[haproxyLegacy]; ok || len(combined.M) != 3 {
+		t.Errorf("Services busy for any provider should be dropped: %v", combined.M)
+	}
+}
+
+func TestIntersectMaps(t *testing.T) {
+	result := map[Namespace]map[Element]string{"shop": {"cart": "", "legacy": ""}, "ops": {"backup": ""}}
+	intersectMaps(result, map[Namespace]map[Element]string{"shop": {"legacy": ""}})
+
+	if _, ok := result["shop"]["legacy"]; !ok || len(result) != 1 || len(result["shop"]) != 1 {
+		t.Errorf("unexpected intersection: %v", result)
+	}
+	if _, ok := result["ops"]; ok {
+		t.Errorf("empty namespaces should be deleted: %v", result)
+	}
+}
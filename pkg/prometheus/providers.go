@@ -0,0 +1,213 @@
+package prometheus
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/prometheus/common/model"
+	"k8s.io/klog"
+)
+
+// IngressProvider describes traffic metrics of an ingress controller (or Gateway API implementation)
+// and how their labels map back to namespace/ingress/host/path, to the route, or to the upstream Service
+// if the controller reports neither
+type IngressProvider struct {
+	Name string
+	// Requests counter
+	Metric string
+	// Additional label matchers, e.g. `reporter="source"`
+	Selector string
+	// Labels to aggregate by
+	Labels []string
+	// Kind of keys: providers with the same kind report the same entries (e.g. Services), other entries can't be
+	// compared
+	KeyKind string
+	// Key maps labels of a series to IngressKey, false if series can't be mapped
+	Key func(metric model.Metric) (IngressKey, bool)
+}
+
+// Kinds of IngressKey entries of providers
+const (
+	KeyKindIngress = "ingress"
+	KeyKindRoute   = "route"
+	KeyKindService = "service"
+	// Service names prefixed with namespace, see SplitNamespacedName
+	KeyKindPrefixedService = "prefixed-service"
+)
+
+// IngressProviders are known ingress controllers and Gateway API implementations in order of auto-detection
+var IngressProviders = []*IngressProvider{
+	{
+		// https://github.com/kubernetes/ingress-nginx
+		Name:     "nginx",
+		Metric:   "nginx_ingress_controller_request_size_count",
+		Selector: `ingress!="",host!="",path!=""`,
+		Labels:   []string{"exported_namespace", "namespace", "ingress", "host", "path"},
+		KeyKind:  KeyKindIngress,
+		Key: func(m model.Metric) (IngressKey, bool) {
+			// `namespace` is overwritten by the controller's namespace unless honor_labels is set
+			ns := m["exported_namespace"]
+			if ns == "" {
+				ns = m["namespace"]
+			}
+			return IngressKey{
+				Namespace: IngNamespace(ns),
+				Ingress:   Ingress(m["ingress"]),
+				Host:      Host(m["host"]),
+				Path:      Path(m["path"]),
+			}, ns != "" && m["ingress"] != ""
+		},
+	},
+	{
+		// https://doc.traefik.io/traefik/observability/metrics/prometheus/
+		// Kubernetes services are named `<namespace>-<service>-<port>@kubernetes(crd)`
+		Name:     "traefik",
+		Metric:   "traefik_service_requests_total",
+		Selector: `service=~".+@kubernetes(crd)?"`,
+		Labels:   []string{"service"},
+		KeyKind:  KeyKindPrefixedService,
+		Key: func(m model.Metric) (IngressKey, bool) {
+			name := string(m["service"])
+			providerPos := strings.LastIndex(name, "@")
+			if providerPos <= 0 {
+				return IngressKey{}, false
+			}
+			name = name[:providerPos]
+			portPos := strings.LastIndex(name, "-")
+			if portPos <= 0 {
+				return IngressKey{}, false
+			}
+			// Namespace and service can't be split by dash here, see SplitNamespacedName
+			return IngressKey{Service: name[:portPos]}, true
+		},
+	},
+	{
+		// https://github.com/haproxytech/kubernetes-ingress, backends are named `<namespace>_<service>_<port>`
+		Name:     "haproxy",
+		Metric:   "haproxy_backend_http_requests_total",
+		Selector: `proxy=~"[^_]+_[^_]+_.+"`,
+		Labels:   []string{"proxy"},
+		KeyKind:  KeyKindService,
+		Key: func(m model.Metric) (IngressKey, bool) {
+			return underscoreServiceKey(string(m["proxy"]))
+		},
+	},
+	{
+		// https://projectcontour.io, Envoy clusters are named `<namespace>/<service>/<port>/<hash>`,
+		// slashes are replaced with underscores in Envoy metrics
+		Name:     "contour",
+		Metric:   "envoy_cluster_upstream_rq_total",
+		Selector: `envoy_cluster_name=~"[^_]+_[^_]+_[^_]+_[^_]+"`,
+		Labels:   []string{"envoy_cluster_name"},
+		KeyKind:  KeyKindService,
+		Key: func(m model.Metric) (IngressKey, bool) {
+			return underscoreServiceKey(string(m["envoy_cluster_name"]))
+		},
+	},
+	{
+		// https://gateway.envoyproxy.io, Envoy clusters are named `<httproute|grpcroute>/<namespace>/<name>/rule/<index>`
+		Name:     "envoy-gateway",
+		Metric:   "envoy_cluster_upstream_rq_total",
+		Selector: `envoy_cluster_name=~"(httproute|grpcroute)/.+"`,
+		Labels:   []string{"envoy_cluster_name"},
+		KeyKind:  KeyKindRoute,
+		Key: func(m model.Metric) (IngressKey, bool) {
+			parts := strings.Split(string(m["envoy_cluster_name"]), "/")
+			if len(parts) < 3 {
+				return IngressKey{}, false
+			}
+			key := IngressKey{Namespace: IngNamespace(parts[1]), Route: parts[2]}
+			switch parts[0] {
+			case "httproute":
+				key.RouteKind = "HTTPRoute"
+			case "grpcroute":
+				key.RouteKind = "GRPCRoute"
+			}
+			if len(parts) >= 5 && parts[3] == "rule" {
+				key.RouteRule = parts[4]
+			}
+			return key, true
+		},
+	},
+	{
+		// https://istio.io/latest/docs/reference/config/metrics/, requests reported by ingress gateways
+		Name:     "istio",
+		Metric:   "istio_requests_total",
+		Selector: `reporter="source",source_workload=~"istio-ingressgateway.*",destination_service_name!=""`,
+		Labels:   []string{"destination_service_namespace", "destination_service_name"},
+		KeyKind:  KeyKindService,
+		Key: func(m model.Metric) (IngressKey, bool) {
+			return IngressKey{
+				Namespace: IngNamespace(m["destination_service_namespace"]),
+				Service:   string(m["destination_service_name"]),
+			}, m["destination_service_namespace"] != "" && m["destination_service_name"] != ""
+		},
+	},
+}
+
+// UnusedQuery returns query of series without requests during the last hour
+func (p *IngressProvider) UnusedQuery() string {
+	return fmt.Sprintf(`sum(rate(%v{%v}[1h])) by (%v) == 0`, p.Metric, p.Selector, strings.Join(p.Labels, ", "))
+}
+
+// GetIngressProviders returns providers by names
+func GetIngressProviders(names []string) ([]*IngressProvider, error) {
+	var providers []*IngressProvider
+	for _, name := range names {
+		found := false
+		for _, p := range IngressProviders {
+			if p.Name == name {
+				providers = append(providers, p)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown ingress provider: %v", name)
+		}
+	}
+
+	return providers, nil
+}
+
+// DetectIngressProviders returns providers whose metrics are present in Prometheus
+func DetectIngressProviders(client *Client) ([]*IngressProvider, error) {
+	var providers []*IngressProvider
+	for _, p := range IngressProviders {
+		found, err := SeriesExist(client, p.Metric, p.Selector)
+		if err != nil {
+			return nil, err
+		}
+		if found {
+			klog.V(3).Infof("Detected ingress provider: %v", p.Name)
+			providers = append(providers, p)
+		}
+	}
+
+	return providers, nil
+}
+
+// underscoreServiceKey parses `<namespace>_<service>_<port>[_...]` names. Kubernetes names can't contain
+// underscores, so they are unambiguous.
+func underscoreServiceKey(name string) (IngressKey, bool) {
+	parts := strings.Split(name, "_")
+	if len(parts) < 3 || parts[0] == "" || parts[1] == "" {
+		return IngressKey{}, false
+	}
+
+	return IngressKey{Namespace: IngNamespace(parts[0]), Service: parts[1]}, true
+}
+
+// SplitNamespacedName splits `<namespace>-<name>` using known namespaces (the longest one wins)
+func SplitNamespacedName(name string, namespaces []string) (namespace, rest string, ok bool) {
+	for _, ns := range namespaces {
+		if strings.HasPrefix(name, ns+"-") && len(ns) > len(namespace) {
+			namespace = ns
+		}
+	}
+	if namespace == "" {
+		return "", "", false
+	}
+
+	return namespace, name[len(namespace)+1:], true
+}
@@ -0,0 +1,39 @@
+package prometheus
+
+import (
+	"github.com/prometheus/common/model"
+	"k8s.io/klog"
+)
+
+// Kubelet reports used bytes of mounted volumes only, so claims without changes in used bytes had no writes.
+// Reads can't be seen in kubelet volume metrics.
+const promQueryVolumes = `sum(changes(kubelet_volume_stats_used_bytes{persistentvolumeclaim!=""}[1h])) ` +
+	`by (namespace, persistentvolumeclaim) == 0`
+
+// GetUnusedVolumeClaims returns PersistentVolumeClaims (Element) with unchanged used bytes according to kubelet
+// volume metrics during the whole observed period, with real observed period in hours
+func GetUnusedVolumeClaims(client *Client, maxSteps int) (map[Namespace]map[Element]string, int, error) {
+	klog.V(4).Infof("Volumes query: %v", promQueryVolumes)
+
+	var resultMap = map[Namespace]map[Element]string{}
+	observedPeriod, err := QueryVectorSteps(client, maxSteps, promQueryVolumes, func(step int, vector model.Vector) {
+		// Temporary map for current step
+		var tempMap = map[Namespace]map[Element]string{}
+		for _, sample := range vector {
+			MapAdd(tempMap, Namespace(sample.Metric["namespace"]), Element(sample.Metric["persistentvolumeclaim"]), "")
+		}
+
+		if step == 0 {
+			resultMap = tempMap
+			return
+		}
+
+		// If used bytes changed on any step, consider the claim as "useful"
+		intersectMaps(resultMap, tempMap)
+	})
+	if err != nil {
+		return map[Namespace]map[Element]string{}, 0, err
+	}
+
+	return resultMap, observedPeriod, nil
+}
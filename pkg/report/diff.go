@@ -0,0 +1,160 @@
+package report
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"time"
+)
+
+// Diff compares two reports
+type Diff struct {
+	Old *Report
+	New *Report
+
+	// Workloads idle in the new report only
+	NewlyIdle []Finding
+	// Workloads idle in the old report only, they are active again or removed
+	Active []Finding
+	// Other findings of the new report only
+	Added []Finding
+	// Other findings of the old report only
+	Resolved []Finding
+	// Changes of reclaimable resources, namespaces without changes are omitted
+	Namespaces []NamespaceChange
+}
+
+// NamespaceChange is a change of reclaimable resources in a namespace, empty namespace stands for
+// cluster-scoped objects
+type NamespaceChange struct {
+	// Set in multi-cluster reports
+	Cluster   string
+	Namespace string
+	CPU       int64
+	Memory    int64
+	Storage   int64
+}
+
+// Compare returns changes from the old report to the current (new) one
+func Compare(old, cur *Report) *Diff {
+	d := &Diff{Old: old, New: cur}
+
+	oldKeys := map[string]bool{}
+	for _, f := range old.Findings {
+		oldKeys[f.Key()] = true
+	}
+	newKeys := map[string]bool{}
+	for _, f := range cur.Findings {
+		newKeys[f.Key()] = true
+	}
+
+	changes := map[string]*NamespaceChange{}
+	change := func(f *Finding, sign int64) {
+		namespace := f.namespace()
+		c, ok := changes[f.Cluster+":"+namespace]
+		if !ok {
+			c = &NamespaceChange{Cluster: f.Cluster, Namespace: namespace}
+			changes[f.Cluster+":"+namespace] = c
+		}
+		c.CPU += sign * f.CPU
+		c.Memory += sign * f.Memory
+		c.Storage += sign * f.Storage
+	}
+
+	for i := range cur.Findings {
+		f := &cur.Findings[i]
+		change(f, 1)
+		if oldKeys[f.Key()] {
+			continue
+		}
+		if f.Category == CategoryIdleWorkload {
+			d.NewlyIdle = append(d.NewlyIdle, *f)
+		} else {
+			d.Added = append(d.Added, *f)
+		}
+	}
+	for i := range old.Findings {
+		f := &old.Findings[i]
+		change(f, -1)
+		if newKeys[f.Key()] {
+			continue
+		}
+		if f.Category == CategoryIdleWorkload {
+			d.Active = append(d.Active, *f)
+		} else {
+			d.Resolved = append(d.Resolved, *f)
+		}
+	}
+
+	for _, c := range changes {
+		if c.CPU != 0 || c.Memory != 0 || c.Storage != 0 {
+			d.Namespaces = append(d.Namespaces, *c)
+		}
+	}
+	sort.Slice(d.Namespaces, func(i, j int) bool {
+		if d.Namespaces[i].Cluster != d.Namespaces[j].Cluster {
+			return d.Namespaces[i].Cluster < d.Namespaces[j].Cluster
+		}
+		return d.Namespaces[i].Namespace < d.Namespaces[j].Namespace
+	})
+	for _, findings := range [][]Finding{d.NewlyIdle, d.Active, d.Added, d.Resolved} {
+		sortFindings(findings)
+	}
+
+	return d
+}
+
+// Worse checks whether the new report has findings the old one hasn't
+func (d *Diff) Worse() bool {
+	return len(d.NewlyIdle) > 0 || len(d.Added) > 0
+}
+
+// Print writes changes grouped by kind of change
+func (d *Diff) Print(w io.Writer) {
+	_, _ = fmt.Fprintf(w, "# %v -> %v: findings: %v -> %v\n\n", reportTime(d.Old), reportTime(d.New),
+		len(d.Old.Findings), len(d.New.Findings))
+
+	for _, group := range []struct {
+		title    string
+		findings []Finding
+	}{
+		{"Newly idle workloads", d.NewlyIdle},
+		{"Workloads active again (or removed)", d.Active},
+		{"New findings", d.Added},
+		{"Resolved findings", d.Resolved},
+	} {
+		if len(group.findings) == 0 {
+			continue
+		}
+		_, _ = fmt.Fprintf(w, "# %v: %v\n", group.title, len(group.findings))
+		for _, f := range group.findings {
+			_, _ = fmt.Fprintln(w, f.String())
+		}
+		_, _ = fmt.Fprintln(w)
+	}
+
+	if len(d.Namespaces) == 0 {
+		return
+	}
+	_, _ = fmt.Fprintln(w, "# Change of reclaimable requests per namespace")
+	for _, c := range d.Namespaces {
+		namespace := c.Namespace
+		if namespace == "" {
+			namespace = "(cluster-scoped)"
+		}
+		if c.Cluster != "" {
+			namespace = c.Cluster + ":" + namespace
+		}
+		_, _ = fmt.Fprintf(w, "%v: CPU: %+.3f, memory (MB): %+d, storage (GB): %+.2f\n", namespace,
+			float64(c.CPU)/1000, c.Memory/1024/1024, float64(c.Storage)/1024/1024/1024)
+	}
+}
+
+// reportTime returns time of the report, reports saved without it are "unknown"
+func reportTime(r *Report) string {
+	if r.Time.IsZero() {
+		return "unknown"
+	}
+
+	return r.Time.Format(time.RFC3339)
+}
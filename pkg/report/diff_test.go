@@ -0,0 +1,64 @@
+package report
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestCompare(t *testing.T) {
+	cart := Finding{Category: CategoryIdleWorkload, Namespace: "shop", Kind: "Deployment", Name: "cart", CPU: 500}
+	legacy := Finding{Category: CategoryIdleWorkload, Namespace: "shop", Kind: "Deployment", Name: "legacy",
+		CPU: 250, Memory: 512 * 1024 * 1024}
+	search := Finding{Category: CategoryIdleWorkload, Namespace: "search", Kind: "StatefulSet", Name: "es",
+		CPU: 2000}
+	data := Finding{Category: CategoryUnusedVolume, Namespace: "shop", Kind: "PersistentVolumeClaim", Name: "data"}
+	pv := Finding{Category: CategoryUnusedVolume, Kind: "PersistentVolume", Name: "pv-1",
+		Storage: 1024 * 1024 * 1024}
+
+	old := &Report{Findings: []Finding{cart, legacy, data}}
+	new := &Report{Findings: []Finding{cart, search, pv}}
+	d := Compare(old, new)
+
+	for _, group := range []struct {
+		name     string
+		findings []Finding
+		expected string
+	}{
+		{"newly idle", d.NewlyIdle, search.Key()},
+		{"active", d.Active, legacy.Key()},
+		{"added", d.Added, pv.Key()},
+		{"resolved", d.Resolved, data.Key()},
+	} {
+		if len(group.findings) != 1 || group.findings[0].Key() != group.expected {
+			t.Errorf("unexpected %v findings: %+v", group.name, group.findings)
+		}
+	}
+
+	expected := []NamespaceChange{
+		{Namespace: "", Storage: 1024 * 1024 * 1024},
+		{Namespace: "search", CPU: 2000},
+		{Namespace: "shop", CPU: -250, Memory: -512 * 1024 * 1024},
+	}
+	if len(d.Namespaces) != len(expected) {
+		t.Fatalf("unexpected namespace changes: %+v", d.Namespaces)
+	}
+	for i := range expected {
+		if d.Namespaces[i] != expected[i] {
+			t.Errorf("unexpected change of namespace %q: %+v", expected[i].Namespace, d.Namespaces[i])
+		}
+	}
+	if !d.Worse() {
+		t.Error("new findings should make the diff worse")
+	}
+
+	var out bytes.Buffer
+	d.Print(&out)
+	if !strings.Contains(out.String(), "shop: CPU: -0.250, memory (MB): -512, storage (GB): +0.00") {
+		t.Errorf("unexpected output:\n%v", out.String())
+	}
+
+	if Compare(new, &Report{Findings: []Finding{cart}}).Worse() {
+		t.Error("only resolved findings shouldn't make the diff worse")
+	}
+}
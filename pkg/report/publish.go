@@ -0,0 +1,203 @@
+package report
+
+import (
+	"sort"
+	"time"
+
+	"github.com/Nastradamus/useless-operator/pkg/apis/useless/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/klog"
+)
+
+// Name of UselessReports in namespaces and of the ClusterUselessReport
+const ReportName = "useless-operator"
+
+// Label selecting reports written by the operator
+const (
+	labelManagedBy = "app.kubernetes.io/managed-by"
+	managedBy      = "useless-operator"
+)
+
+// Publisher writes findings into UselessReport and ClusterUselessReport objects
+type Publisher struct {
+	dClient dynamic.Interface
+}
+
+// NewPublisher returns publisher writing reports via the dynamic client
+func NewPublisher(dClient dynamic.Interface) *Publisher {
+	return &Publisher{dClient: dClient}
+}
+
+// Publish writes a report per namespace with findings and the cluster-wide summary, reports of namespaces
+// without findings are deleted. Returns number of namespace reports written. Missing CRDs disable publishing.
+func (p *Publisher) Publish(r *Report, window time.Duration, scan time.Time) (int, error) {
+	namespaces, cluster := Statuses(r, window, scan)
+
+	existing, err := p.dClient.Resource(v1alpha1.UselessReportResource).Namespace("").List(metav1.ListOptions{
+		LabelSelector: labelManagedBy + "=" + managedBy,
+	})
+	if errors.IsNotFound(err) {
+		klog.V(2).Infof("%v aren't served, CRD isn't installed", v1alpha1.UselessReportResource.Resource)
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	written := 0
+	for namespace, status := range namespaces {
+		if err := p.write(v1alpha1.UselessReportResource, namespace, status); err != nil {
+			klog.Warningf("Can't write report of namespace %v: %v", namespace, err)
+			continue
+		}
+		written++
+	}
+
+	for _, item := range existing.Items {
+		if _, ok := namespaces[item.GetNamespace()]; ok || item.GetName() != ReportName {
+			continue
+		}
+		err := p.dClient.Resource(v1alpha1.UselessReportResource).Namespace(item.GetNamespace()).
+			Delete(item.GetName(), &metav1.DeleteOptions{})
+		if err != nil && !errors.IsNotFound(err) {
+			klog.Warningf("Can't delete report of namespace %v: %v", item.GetNamespace(), err)
+		}
+	}
+
+	return written, p.write(v1alpha1.ClusterUselessReportResource, "", cluster)
+}
+
+// write creates the report if it doesn't exist and replaces its status
+func (p *Publisher) write(gvr schema.GroupVersionResource, namespace string, status interface{}) error {
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(status)
+	if err != nil {
+		return err
+	}
+
+	client := p.dClient.Resource(gvr).Namespace(namespace)
+	obj, err := client.Get(ReportName, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		kind := "UselessReport"
+		if namespace == "" {
+			kind = "ClusterUselessReport"
+		}
+		obj = &unstructured.Unstructured{}
+		obj.SetAPIVersion(gvr.GroupVersion().String())
+		obj.SetKind(kind)
+		obj.SetNamespace(namespace)
+		obj.SetName(ReportName)
+		obj.SetLabels(map[string]string{labelManagedBy: managedBy})
+		// Status is ignored on creation
+		obj, err = client.Create(obj, metav1.CreateOptions{})
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := unstructured.SetNestedField(obj.Object, content, "status"); err != nil {
+		return err
+	}
+	_, err = client.UpdateStatus(obj, metav1.UpdateOptions{})
+
+	return err
+}
+
+// Statuses returns statuses of namespaces' reports and of the cluster-wide summary. Findings of namespaces
+// themselves are reported in the namespace, findings of other cluster-scoped objects in the summary.
+func Statuses(r *Report, window time.Duration, scan time.Time) (map[string]*v1alpha1.UselessReportStatus,
+	*v1alpha1.ClusterUselessReportStatus) {
+
+	scanTime := metav1.NewTime(scan)
+	observed := metav1.Duration{Duration: window}
+	cluster := &v1alpha1.ClusterUselessReportStatus{ScanTime: scanTime, ObservedWindow: observed}
+	namespaces := map[string]*v1alpha1.UselessReportStatus{}
+
+	findings := make([]Finding, len(r.Findings))
+	copy(findings, r.Findings)
+	sortFindings(findings)
+	for i := range findings {
+		f := &findings[i]
+		addToSummary(&cluster.Summary, f)
+
+		namespace := f.namespace()
+		if namespace == "" {
+			cluster.Findings = append(cluster.Findings, reportFinding(f))
+			continue
+		}
+		status, ok := namespaces[namespace]
+		if !ok {
+			status = &v1alpha1.UselessReportStatus{ScanTime: scanTime, ObservedWindow: observed}
+			namespaces[namespace] = status
+		}
+		status.Findings = append(status.Findings, reportFinding(f))
+		addToSummary(&status.Summary, f)
+	}
+
+	for namespace, status := range namespaces {
+		cluster.Namespaces = append(cluster.Namespaces, v1alpha1.NamespaceSummary{
+			Namespace:     namespace,
+			ReportSummary: status.Summary,
+		})
+	}
+	sort.Slice(cluster.Namespaces, func(i, j int) bool {
+		return cluster.Namespaces[i].Namespace < cluster.Namespaces[j].Namespace
+	})
+
+	return namespaces, cluster
+}
+
+// namespace returns namespace the finding belongs to, namespaces belong to themselves. Empty for other
+// cluster-scoped objects.
+func (f *Finding) namespace() string {
+	if f.Kind == "Namespace" {
+		return f.Name
+	}
+
+	return f.Namespace
+}
+
+// addToSummary adds the finding and its reclaimable resources to the summary
+func addToSummary(s *v1alpha1.ReportSummary, f *Finding) {
+	s.Findings++
+	s.CPU.Add(*resource.NewMilliQuantity(f.CPU, resource.DecimalSI))
+	s.Memory.Add(*resource.NewQuantity(f.Memory, resource.BinarySI))
+	s.Storage.Add(*resource.NewQuantity(f.Storage, resource.BinarySI))
+}
+
+// reportFinding converts the finding for reports
+func reportFinding(f *Finding) v1alpha1.ReportFinding {
+	rf := v1alpha1.ReportFinding{
+		Category: string(f.Category),
+		Kind:     f.Kind,
+		Name:     f.Name,
+		Subject:  f.Subject,
+		Reason:   f.Reason,
+		Owner:    f.Owner,
+		State:    f.State,
+		Command:  f.Command,
+	}
+	if f.FirstSeen != nil {
+		firstSeen := metav1.NewTime(*f.FirstSeen)
+		rf.FirstSeen, rf.Streak = &firstSeen, f.Streak
+	}
+	for _, signal := range f.Signals {
+		rf.Signals = append(rf.Signals, string(signal))
+	}
+	if f.CPU > 0 {
+		rf.CPU = resource.NewMilliQuantity(f.CPU, resource.DecimalSI)
+	}
+	if f.Memory > 0 {
+		rf.Memory = resource.NewQuantity(f.Memory, resource.BinarySI)
+	}
+	if f.Storage > 0 {
+		rf.Storage = resource.NewQuantity(f.Storage, resource.BinarySI)
+	}
+
+	return rf
+}
@@ -0,0 +1,59 @@
+package report
+
+import (
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestStatuses(t *testing.T) {
+	r := &Report{}
+	r.Add(
+		Finding{Category: CategoryIdleWorkload, Namespace: "shop", Kind: "Deployment", Name: "cart",
+			CPU: 500, Memory: 256 * 1024 * 1024, Signals: []Signal{SignalPodTraffic}},
+		Finding{Category: CategoryUnusedVolume, Namespace: "shop", Kind: "PersistentVolumeClaim", Name: "data",
+			Storage: 10 * 1024 * 1024 * 1024},
+		Finding{Category: CategoryAbandonedNamespace, Kind: "Namespace", Name: "legacy"},
+		Finding{Category: CategoryUnusedVolume, Kind: "PersistentVolume", Name: "pv-1", Storage: 1024 * 1024 * 1024},
+	)
+
+	namespaces, cluster := Statuses(r, 6*time.Hour, time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	if len(namespaces) != 2 {
+		t.Fatalf("expected reports of shop and legacy, got %v", len(namespaces))
+	}
+	shop := namespaces["shop"]
+	if shop.Summary.Findings != 2 || shop.Summary.CPU.String() != "500m" || shop.Summary.Memory.String() != "256Mi" ||
+		shop.Summary.Storage.String() != "10Gi" {
+		t.Errorf("unexpected summary of shop: %+v", shop.Summary)
+	}
+	if len(namespaces["legacy"].Findings) != 1 {
+		t.Errorf("abandoned namespace should be reported in itself: %+v", namespaces["legacy"])
+	}
+	if len(cluster.Findings) != 1 || cluster.Findings[0].Name != "pv-1" {
+		t.Errorf("only cluster-scoped objects should be listed in the summary: %+v", cluster.Findings)
+	}
+	if cluster.Summary.Findings != 4 || cluster.Summary.Storage.String() != "11Gi" {
+		t.Errorf("unexpected cluster summary: %+v", cluster.Summary)
+	}
+	if len(cluster.Namespaces) != 2 || cluster.Namespaces[0].Namespace != "legacy" {
+		t.Errorf("unexpected namespace summaries: %+v", cluster.Namespaces)
+	}
+
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(cluster)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cpu, _, _ := unstructured.NestedString(content, "summary", "cpu"); cpu != "500m" {
+		t.Errorf("unexpected cpu in status: %v", content["summary"])
+	}
+	namespaceSummaries, _, _ := unstructured.NestedSlice(content, "namespaces")
+	if storage := namespaceSummaries[1].(map[string]interface{})["storage"]; storage != "10Gi" {
+		t.Errorf("namespace summary should be inlined: %v", namespaceSummaries[1])
+	}
+	if window, _, _ := unstructured.NestedString(content, "observedWindow"); window != "6h0m0s" {
+		t.Errorf("unexpected observed window: %v", window)
+	}
+}
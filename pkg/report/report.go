@@ -0,0 +1,249 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Category is a kind of waste found by a detector
+type Category string
+
+const (
+	CategoryIdleWorkload       Category = "idle-workload"
+	CategoryIdleRoute          Category = "idle-route"
+	CategoryDanglingIngress    Category = "dangling-ingress"
+	CategoryUnusedVolume       Category = "unused-volume"
+	CategoryUnreferencedConfig Category = "unreferenced-config"
+	CategoryStaleJob           Category = "stale-job"
+	CategoryPinnedHPA          Category = "pinned-hpa"
+	CategoryAbandonedNamespace Category = "abandoned-namespace"
+)
+
+// Owner of findings without owner annotations and labels
+const UnknownOwner = "unknown"
+
+// Finding is a single object considered useless by a detector
+type Finding struct {
+	// Context of the cluster in multi-cluster scans
+	Cluster   string   `json:"cluster,omitempty"`
+	Category  Category `json:"category"`
+	Namespace string   `json:"namespace,omitempty"`
+	Kind      string   `json:"kind"`
+	Name      string   `json:"name"`
+	// Part of the object the finding is about, e.g. ingress rule's host and path
+	Subject string            `json:"subject,omitempty"`
+	Reason  string            `json:"reason"`
+	Signals []Signal          `json:"signals,omitempty"`
+	Details map[string]string `json:"details,omitempty"`
+	// Reclaimable resources
+	CPU     int64 `json:"cpuMilli,omitempty"`
+	Memory  int64 `json:"memoryBytes,omitempty"`
+	Storage int64 `json:"storageBytes,omitempty"`
+	// kubectl command which frees the resources
+	Command string `json:"command,omitempty"`
+	Owner   string `json:"owner,omitempty"`
+	// Lifecycle state of idle workloads
+	State string `json:"state,omitempty"`
+	// Start of the streak of consecutive scans finding the object, set by scan history
+	FirstSeen *time.Time `json:"firstSeen,omitempty"`
+	Streak    int        `json:"streak,omitempty"`
+}
+
+// Report is a result of a scan
+type Report struct {
+	// Time the scan finished, set when the report is saved
+	Time     time.Time `json:"time,omitempty"`
+	Findings []Finding `json:"findings"`
+}
+
+// Load reads report saved as JSON
+func Load(path string) (*Report, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	r := &Report{}
+	if err := json.Unmarshal(data, r); err != nil {
+		return nil, fmt.Errorf("can't parse report %v: %v", path, err)
+	}
+
+	return r, nil
+}
+
+// Save writes the report as JSON
+func (r *Report) Save(path string) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(path, data, 0644)
+}
+
+// TeamSummary sums up findings of one owner
+type TeamSummary struct {
+	Owner    string
+	Findings []Finding
+	CPU      int64
+	Memory   int64
+	Storage  int64
+}
+
+// Key identifies the finding across scans
+func (f *Finding) Key() string {
+	key := strings.Join([]string{string(f.Category), f.Namespace, f.Kind, f.Name, f.Subject}, "/")
+	if f.Cluster != "" {
+		key = f.Cluster + ":" + key
+	}
+
+	return key
+}
+
+// Add adds findings to the report
+func (r *Report) Add(findings ...Finding) {
+	r.Findings = append(r.Findings, findings...)
+}
+
+// Clusters returns sorted clusters of findings, none for single cluster scans
+func (r *Report) Clusters() []string {
+	seen := map[string]bool{}
+	var clusters []string
+	for _, f := range r.Findings {
+		if f.Cluster != "" && !seen[f.Cluster] {
+			seen[f.Cluster] = true
+			clusters = append(clusters, f.Cluster)
+		}
+	}
+	sort.Strings(clusters)
+
+	return clusters
+}
+
+// Cluster returns report with findings of the cluster only
+func (r *Report) Cluster(name string) *Report {
+	cluster := &Report{Time: r.Time}
+	for _, f := range r.Findings {
+		if f.Cluster == name {
+			cluster.Findings = append(cluster.Findings, f)
+		}
+	}
+
+	return cluster
+}
+
+// ByOwner returns findings grouped by owner, owners with more reclaimable CPU first
+func (r *Report) ByOwner() []TeamSummary {
+	teams := map[string]*TeamSummary{}
+	for _, f := range r.Findings {
+		owner := f.Owner
+		if owner == "" {
+			owner = UnknownOwner
+		}
+		team, ok := teams[owner]
+		if !ok {
+			team = &TeamSummary{Owner: owner}
+			teams[owner] = team
+		}
+		team.Findings = append(team.Findings, f)
+		team.CPU += f.CPU
+		team.Memory += f.Memory
+		team.Storage += f.Storage
+	}
+
+	summaries := make([]TeamSummary, 0, len(teams))
+	for _, team := range teams {
+		sortFindings(team.Findings)
+		summaries = append(summaries, *team)
+	}
+	sort.Slice(summaries, func(i, j int) bool {
+		if summaries[i].CPU != summaries[j].CPU {
+			return summaries[i].CPU > summaries[j].CPU
+		}
+		return summaries[i].Owner < summaries[j].Owner
+	})
+
+	return summaries
+}
+
+// Print writes findings grouped and summarized per owner, per cluster first in multi-cluster scans
+func (r *Report) Print(w io.Writer) {
+	clusters := r.Clusters()
+	if len(clusters) == 0 {
+		r.printOwners(w)
+		return
+	}
+	for _, name := range clusters {
+		_, _ = fmt.Fprintf(w, "## cluster: %v\n\n", name)
+		r.Cluster(name).printOwners(w)
+	}
+}
+
+// printOwners writes findings grouped and summarized per owner
+func (r *Report) printOwners(w io.Writer) {
+	for _, team := range r.ByOwner() {
+		_, _ = fmt.Fprintf(w, "# %v\n", team.Summary())
+		for _, f := range team.Findings {
+			_, _ = fmt.Fprintln(w, f.String())
+			if f.Command != "" {
+				_, _ = fmt.Fprintln(w, f.Command)
+			}
+		}
+		_, _ = fmt.Fprintln(w)
+	}
+}
+
+// Summary returns one line summary of team's findings
+func (team *TeamSummary) Summary() string {
+	return fmt.Sprintf("owner: %v, findings: %v, requests: CPU: %v, memory (MB): %v, storage (GB): %.2f",
+		team.Owner, len(team.Findings), float64(team.CPU)/1000, team.Memory/1024/1024,
+		float64(team.Storage)/1024/1024/1024)
+}
+
+// String returns one line description of the finding
+func (f *Finding) String() string {
+	name := f.Name
+	if f.Namespace != "" {
+		name = f.Namespace + "/" + f.Name
+	}
+	if f.Cluster != "" {
+		name = f.Cluster + ":" + name
+	}
+	s := fmt.Sprintf("[%v] %v %v", f.Category, f.Kind, name)
+	if f.Subject != "" {
+		s += " (" + f.Subject + ")"
+	}
+	s += ": " + f.Reason
+	if f.State != "" {
+		s += ", state: " + f.State
+	}
+	if f.FirstSeen != nil && f.Streak > 1 {
+		s += fmt.Sprintf(", found since: %v (%v scans)", f.FirstSeen.Format(time.RFC3339), f.Streak)
+	}
+
+	if len(f.Signals) > 0 {
+		s += ", signals: " + SignalsString(f.Signals)
+	}
+
+	keys := make([]string, 0, len(f.Details))
+	for key := range f.Details {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		s += fmt.Sprintf(", %v: %v", key, f.Details[key])
+	}
+
+	return s
+}
+
+// sortFindings sorts findings by cluster, category, namespace, kind and name
+func sortFindings(findings []Finding) {
+	sort.SliceStable(findings, func(i, j int) bool {
+		return findings[i].Key() < findings[j].Key()
+	})
+}
@@ -0,0 +1,34 @@
+package report
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestPrintClusters(t *testing.T) {
+	eu := Finding{Cluster: "prod-eu", Category: CategoryIdleWorkload, Namespace: "shop", Kind: "Deployment",
+		Name: "cart", Reason: "no traffic"}
+	us := eu
+	us.Cluster = "prod-us"
+	r := &Report{Findings: []Finding{us, eu}}
+
+	if eu.Key() == us.Key() {
+		t.Errorf("findings of different clusters share key %v", eu.Key())
+	}
+	if clusters := r.Clusters(); len(clusters) != 2 || clusters[0] != "prod-eu" || clusters[1] != "prod-us" {
+		t.Errorf("unexpected clusters: %v", clusters)
+	}
+
+	var out bytes.Buffer
+	r.Print(&out)
+	printed := out.String()
+	euAt := strings.Index(printed, "## cluster: prod-eu")
+	usAt := strings.Index(printed, "## cluster: prod-us")
+	if euAt < 0 || usAt < euAt {
+		t.Errorf("clusters aren't printed in order:\n%v", printed)
+	}
+	if !strings.Contains(printed, "Deployment prod-us:shop/cart: no traffic") {
+		t.Errorf("finding doesn't tell its cluster:\n%v", printed)
+	}
+}
@@ -0,0 +1,164 @@
+package report
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	ukube "github.com/Nastradamus/useless-operator/pkg/ukubernetes"
+)
+
+// Signal is a detector which considers a workload idle
+type Signal string
+
+const (
+	// No outgoing traffic of workload's pods
+	SignalPodTraffic Signal = "pod-traffic"
+	// No requests through ingresses pointing at workload's pods
+	SignalIngressTraffic Signal = "ingress-traffic"
+	// No requests through Gateway API routes pointing at workload's pods
+	SignalRouteTraffic Signal = "route-traffic"
+	// No application requests according to service mesh metrics
+	SignalMeshTraffic Signal = "mesh-traffic"
+)
+
+// IdleWorkload is a workload flagged by one or more signals
+type IdleWorkload struct {
+	Namespace string   `json:"namespace"`
+	Kind      string   `json:"kind"`
+	Name      string   `json:"name"`
+	Signals   []Signal `json:"signals"`
+	Pods      []string `json:"pods"`
+	CPU       int64    `json:"cpuMilli"`    // requests of all pods
+	Memory    int64    `json:"memoryBytes"` // requests of all pods
+	// HorizontalPodAutoscaler scaling the workload, it would scale the workload back up
+	HPA string `json:"hpa,omitempty"`
+}
+
+// WorkloadSet merges idle workloads found by different signals
+type WorkloadSet struct {
+	m map[string]*IdleWorkload
+}
+
+// Add adds workload's pod flagged by signal. Every pod's requests are counted once.
+func (s *WorkloadSet) Add(namespace, kind, name string, signal Signal, pod string, cpu, mem int64) {
+	if s.m == nil {
+		s.m = make(map[string]*IdleWorkload)
+	}
+
+	key := namespace + "/" + kind + "/" + name
+	w, ok := s.m[key]
+	if !ok {
+		w = &IdleWorkload{Namespace: namespace, Kind: kind, Name: name}
+		s.m[key] = w
+	}
+
+	if !w.HasSignal(signal) {
+		w.Signals = append(w.Signals, signal)
+	}
+
+	for _, p := range w.Pods {
+		if p == pod {
+			return
+		}
+	}
+	w.Pods = append(w.Pods, pod)
+	w.CPU += cpu
+	w.Memory += mem
+}
+
+// Len returns count of workloads
+func (s *WorkloadSet) Len() int {
+	return len(s.m)
+}
+
+// List returns workloads sorted by namespace, kind and name
+func (s *WorkloadSet) List() []*IdleWorkload {
+	list := make([]*IdleWorkload, 0, len(s.m))
+	for _, w := range s.m {
+		list = append(list, w)
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Namespace != list[j].Namespace {
+			return list[i].Namespace < list[j].Namespace
+		}
+		if list[i].Kind != list[j].Kind {
+			return list[i].Kind < list[j].Kind
+		}
+		return list[i].Name < list[j].Name
+	})
+
+	return list
+}
+
+// HasSignal checks whether workload was flagged by signal
+func (w *IdleWorkload) HasSignal(signal Signal) bool {
+	for _, s := range w.Signals {
+		if s == signal {
+			return true
+		}
+	}
+
+	return false
+}
+
+// SignalsString returns comma-separated signals
+func (w *IdleWorkload) SignalsString() string {
+	return SignalsString(w.Signals)
+}
+
+// SignalsString returns comma-separated signals
+func SignalsString(signals []Signal) string {
+	s := make([]string, 0, len(signals))
+	for _, signal := range signals {
+		s = append(s, string(signal))
+	}
+
+	return strings.Join(s, ",")
+}
+
+// Command returns kubectl command which frees workload's resources
+func (w *IdleWorkload) Command() string {
+	kind := strings.ToLower(w.Kind)
+
+	switch w.Kind {
+	case "Deployment", "StatefulSet", "ReplicaSet", "ReplicationController":
+		scale := fmt.Sprintf("kubectl -n %v scale %v %v --replicas=0", w.Namespace, kind, w.Name)
+		if w.HPA == "" {
+			return scale
+		}
+		// HPA can't scale to zero and would scale the workload back up, save and delete it first
+		return fmt.Sprintf("kubectl -n %v get hpa %v -o yaml > hpa-%v-%v.yaml && kubectl -n %v delete hpa %v && %v",
+			w.Namespace, w.HPA, w.Namespace, w.HPA, w.Namespace, w.HPA, scale)
+	case "DaemonSet":
+		// DaemonSets can't be scaled, make them match no nodes instead
+		return fmt.Sprintf(`kubectl -n %v patch daemonset %v -p '{"spec":{"template":{"spec":`+
+			`{"nodeSelector":{"%v":"true"}}}}}'`, w.Namespace, w.Name, ukube.ScaledDownNodeSelector)
+	case "CronJob":
+		return fmt.Sprintf(`kubectl -n %v patch cronjob %v -p '{"spec":{"suspend":true}}'`, w.Namespace, w.Name)
+	default:
+		return fmt.Sprintf("kubectl -n %v delete %v %v", w.Namespace, kind, w.Name)
+	}
+}
+
+// Finding returns report finding of the workload
+func (w *IdleWorkload) Finding() Finding {
+	f := Finding{
+		Category:  CategoryIdleWorkload,
+		Namespace: w.Namespace,
+		Kind:      w.Kind,
+		Name:      w.Name,
+		Reason:    "no traffic",
+		Signals:   w.Signals,
+		CPU:       w.CPU,
+		Memory:    w.Memory,
+		Command:   w.Command(),
+		Details:   map[string]string{"pods": fmt.Sprint(len(w.Pods))},
+	}
+	if w.HPA != "" {
+		f.Details["hpa"] = w.HPA
+	}
+
+	return f
+}
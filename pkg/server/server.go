@@ -0,0 +1,122 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Nastradamus/useless-operator/pkg/report"
+)
+
+// Status is the state of the operator served to health checks and Prometheus
+type Status struct {
+	mu       sync.RWMutex
+	leader   bool
+	lastScan time.Time
+	findings map[report.Category]int
+	cpu      int64
+	memory   int64
+	storage  int64
+}
+
+// HealthCheck fails when the operator is unhealthy, e.g. it leads without renewing its lease
+type HealthCheck func(r *http.Request) error
+
+// NewStatus returns status of an operator which hasn't scanned yet
+func NewStatus() *Status {
+	return &Status{findings: map[report.Category]int{}}
+}
+
+// SetLeader records whether the operator is the one performing scans and actions
+func (s *Status) SetLeader(leader bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.leader = leader
+}
+
+// Leader checks whether the operator is the one performing scans and actions
+func (s *Status) Leader() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.leader
+}
+
+// SetReport records result of a finished scan
+func (s *Status) SetReport(r *report.Report, scan time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lastScan = scan
+	s.findings = map[report.Category]int{}
+	s.cpu, s.memory, s.storage = 0, 0, 0
+	for _, f := range r.Findings {
+		s.findings[f.Category]++
+		s.cpu += f.CPU
+		s.memory += f.Memory
+		s.storage += f.Storage
+	}
+}
+
+// Handler serves /healthz and /metrics in Prometheus text format
+func (s *Status) Handler(checks ...HealthCheck) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		for _, check := range checks {
+			if err := check(r); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+		_, _ = fmt.Fprintln(w, "ok")
+	})
+	mux.HandleFunc("/metrics", s.serveMetrics)
+
+	return mux
+}
+
+// serveMetrics writes metrics of the last scan, followers only report they aren't leading
+func (s *Status) serveMetrics(w http.ResponseWriter, _ *http.Request) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	leader := 0
+	if s.leader {
+		leader = 1
+	}
+	gauge(w, "useless_operator_leader", "Whether this replica performs scans and actions.", float64(leader))
+	if s.lastScan.IsZero() {
+		return
+	}
+
+	gauge(w, "useless_operator_last_scan_timestamp_seconds", "Time the last scan finished.",
+		float64(s.lastScan.Unix()))
+
+	categories := make([]string, 0, len(s.findings))
+	for category := range s.findings {
+		categories = append(categories, string(category))
+	}
+	sort.Strings(categories)
+	_, _ = fmt.Fprintln(w, "# HELP useless_operator_findings Findings of the last scan.")
+	_, _ = fmt.Fprintln(w, "# TYPE useless_operator_findings gauge")
+	for _, category := range categories {
+		_, _ = fmt.Fprintf(w, "useless_operator_findings{category=%q} %v\n", category,
+			s.findings[report.Category(category)])
+	}
+
+	gauge(w, "useless_operator_reclaimable_cpu_cores", "CPU requests of findings of the last scan.",
+		float64(s.cpu)/1000)
+	gauge(w, "useless_operator_reclaimable_memory_bytes", "Memory requests of findings of the last scan.",
+		float64(s.memory))
+	gauge(w, "useless_operator_reclaimable_storage_bytes", "Storage of findings of the last scan.",
+		float64(s.storage))
+}
+
+// gauge writes a gauge without labels
+func gauge(w http.ResponseWriter, name, help string, value float64) {
+	_, _ = fmt.Fprintf(w, "# HELP %v %v\n# TYPE %v gauge\n%v %v\n", name, help, name, name, value)
+}
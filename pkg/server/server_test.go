@@ -0,0 +1,68 @@
+package server
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Nastradamus/useless-operator/pkg/report"
+)
+
+func get(t *testing.T, handler http.Handler, path string) (int, string) {
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+	body, err := ioutil.ReadAll(recorder.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return recorder.Code, string(body)
+}
+
+func TestMetrics(t *testing.T) {
+	status := NewStatus()
+
+	_, body := get(t, status.Handler(), "/metrics")
+	if !strings.Contains(body, "useless_operator_leader 0\n") || strings.Contains(body, "findings") {
+		t.Errorf("follower should only report it isn't leading:\n%v", body)
+	}
+
+	status.SetLeader(true)
+	status.SetReport(&report.Report{Findings: []report.Finding{
+		{Category: report.CategoryIdleWorkload, CPU: 1500, Memory: 1024},
+		{Category: report.CategoryIdleWorkload, CPU: 500},
+		{Category: report.CategoryUnusedVolume, Storage: 2048},
+	}}, time.Unix(1600000000, 0))
+
+	_, body = get(t, status.Handler(), "/metrics")
+	for _, line := range []string{
+		"useless_operator_leader 1",
+		"useless_operator_last_scan_timestamp_seconds 1.6e+09",
+		`useless_operator_findings{category="idle-workload"} 2`,
+		`useless_operator_findings{category="unused-volume"} 1`,
+		"useless_operator_reclaimable_cpu_cores 2",
+		"useless_operator_reclaimable_memory_bytes 1024",
+		"useless_operator_reclaimable_storage_bytes 2048",
+	} {
+		if !strings.Contains(body, line+"\n") {
+			t.Errorf("metrics miss %q:\n%v", line, body)
+		}
+	}
+}
+
+func TestHealthz(t *testing.T) {
+	status := NewStatus()
+	if code, _ := get(t, status.Handler(), "/healthz"); code != http.StatusOK {
+		t.Errorf("expected healthy operator, got %v", code)
+	}
+
+	failing := func(*http.Request) error { return errors.New("lease not renewed") }
+	if code, body := get(t, status.Handler(failing), "/healthz"); code != http.StatusInternalServerError ||
+		!strings.Contains(body, "lease not renewed") {
+		t.Errorf("expected failed health check, got %v: %v", code, body)
+	}
+}
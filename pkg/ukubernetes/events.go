@@ -0,0 +1,142 @@
+package ukubernetes
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/tools/reference"
+	"k8s.io/klog"
+)
+
+// Component reported as source of the events
+const EventComponent = "useless-operator"
+
+// Reasons of events recorded on flagged objects
+const (
+	EventReasonIdle       = "Idle"
+	EventReasonDangling   = "Dangling"
+	EventReasonScaledDown = "ScaledDown"
+	EventReasonRestored   = "Restored"
+)
+
+// How long Shutdown waits for recorded events to be sent
+const eventsFlushTimeout = 10 * time.Second
+
+// Reason of the event marking the end of recorded events, it isn't sent
+const eventReasonFlushed = "Flushed"
+
+// EventRecorder records Kubernetes Events on objects. Repeated events are aggregated by the client-go
+// event correlator into one Event with increasing count.
+type EventRecorder struct {
+	// Events recorded and handed to the broadcaster, first for 64-bit alignment of atomic access
+	recorded    int64
+	broadcasted int64
+
+	dClient     dynamic.Interface
+	broadcaster record.EventBroadcaster
+	recorder    record.EventRecorder
+	watchers    []watch.Interface
+	sink        *flushingSink
+
+	resources *servedResources
+}
+
+// NewEventRecorder returns recorder reporting host as source, Shutdown stops it
+func NewEventRecorder(kClient *kubernetes.Clientset, dClient dynamic.Interface, host string) *EventRecorder {
+	r := newEventRecorder(kClient.CoreV1().Events(""), host)
+	r.dClient, r.resources = dClient, newServedResources(kClient)
+
+	return r
+}
+
+// newEventRecorder returns recorder sending events to the client
+func newEventRecorder(events typedcorev1.EventInterface, host string) *EventRecorder {
+	broadcaster := record.NewBroadcaster()
+	sink := &flushingSink{EventSinkImpl: typedcorev1.EventSinkImpl{Interface: events}, flushed: make(chan struct{})}
+
+	r := &EventRecorder{
+		broadcaster: broadcaster,
+		recorder:    broadcaster.NewRecorder(scheme.Scheme, v1.EventSource{Component: EventComponent, Host: host}),
+		sink:        sink,
+	}
+	r.watchers = []watch.Interface{
+		broadcaster.StartRecordingToSink(sink),
+		broadcaster.StartEventWatcher(func(*v1.Event) { atomic.AddInt64(&r.broadcasted, 1) }),
+	}
+
+	return r
+}
+
+// Reference returns reference to the object, kubectl describe finds events by object's UID
+func (r *EventRecorder) Reference(namespace, kind, name string) (*v1.ObjectReference, error) {
+	gvr, err := r.resources.get(kind)
+	if err != nil {
+		return nil, fmt.Errorf("can't record events: %v", err)
+	}
+
+	obj, err := r.dClient.Resource(gvr).Namespace(namespace).Get(name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	return reference.GetReference(scheme.Scheme, obj)
+}
+
+// Eventf records event of eventType (v1.EventTypeNormal or v1.EventTypeWarning) on the object. Events are
+// sent in background, failures are logged by client-go.
+func (r *EventRecorder) Eventf(ref *v1.ObjectReference, eventType, reason, messageFmt string,
+	args ...interface{}) {
+
+	atomic.AddInt64(&r.recorded, 1)
+	r.recorder.Eventf(ref, eventType, reason, messageFmt, args...)
+}
+
+// Shutdown waits for recorded events to be sent (at most eventsFlushTimeout) and stops sending events.
+// The recorder can't be used afterwards.
+func (r *EventRecorder) Shutdown() {
+	deadline := time.Now().Add(eventsFlushTimeout)
+	// Recorder hands events to the broadcaster in background, the end marker must follow them
+	for atomic.LoadInt64(&r.broadcasted) < atomic.LoadInt64(&r.recorded) && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	// Events reach the sink one by one in order they were broadcasted, retries included
+	r.recorder.Event(&v1.ObjectReference{Kind: eventReasonFlushed, Name: EventComponent}, v1.EventTypeNormal,
+		eventReasonFlushed, "")
+	select {
+	case <-r.sink.flushed:
+	case <-time.After(time.Until(deadline)):
+		klog.Warningf("Events weren't sent in %v", eventsFlushTimeout)
+	}
+
+	for _, w := range r.watchers {
+		w.Stop()
+	}
+	// Release goroutine of the broadcaster, client-go doesn't expose it in EventBroadcaster
+	if b, ok := r.broadcaster.(interface{ Shutdown() }); ok {
+		b.Shutdown()
+	}
+}
+
+// flushingSink sends events to the API except the one marking the end of recorded events
+type flushingSink struct {
+	typedcorev1.EventSinkImpl
+	flushed chan struct{}
+}
+
+func (s *flushingSink) Create(event *v1.Event) (*v1.Event, error) {
+	if event.Reason == eventReasonFlushed && event.InvolvedObject.Kind == eventReasonFlushed {
+		close(s.flushed)
+		return event, nil
+	}
+
+	return s.EventSinkImpl.Create(event)
+}
@@ -0,0 +1,56 @@
+package ukubernetes
+
+import (
+	"sync"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+)
+
+// fakeEvents keeps events the sink writes
+type fakeEvents struct {
+	typedcorev1.EventInterface
+
+	mu      sync.Mutex
+	created []*v1.Event
+	patched int
+}
+
+func (f *fakeEvents) CreateWithEventNamespace(event *v1.Event) (*v1.Event, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.created = append(f.created, event)
+
+	return event, nil
+}
+
+func (f *fakeEvents) UpdateWithEventNamespace(event *v1.Event) (*v1.Event, error) {
+	return event, nil
+}
+
+func (f *fakeEvents) PatchWithEventNamespace(event *v1.Event, data []byte) (*v1.Event, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.patched++
+
+	return event, nil
+}
+
+func TestEventRecorderShutdown(t *testing.T) {
+	events := &fakeEvents{}
+	r := newEventRecorder(events, "scanner")
+
+	ref := &v1.ObjectReference{Kind: KindDeployment, Namespace: "shop", Name: "cart", UID: "cart-uid"}
+	for i := 0; i < 3; i++ {
+		r.Eventf(ref, v1.EventTypeWarning, EventReasonIdle, "No traffic for the observed %vh", 24)
+	}
+	r.Shutdown()
+
+	events.mu.Lock()
+	defer events.mu.Unlock()
+	if len(events.created) != 1 || events.created[0].Reason != EventReasonIdle || events.patched != 2 {
+		t.Errorf("repeated events should be aggregated and sent before shutdown, created: %+v, patched: %v",
+			events.created, events.patched)
+	}
+}
@@ -0,0 +1,272 @@
+package ukubernetes
+
+import (
+	"fmt"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog"
+)
+
+// Gateway API versions in order of preference
+var gatewayGroupVersions = []schema.GroupVersion{
+	{Group: "gateway.networking.k8s.io", Version: "v1"},
+	{Group: "gateway.networking.k8s.io", Version: "v1beta1"},
+	{Group: "gateway.networking.k8s.io", Version: "v1alpha2"},
+}
+
+// Gateway API route kinds
+const (
+	KindHTTPRoute = "HTTPRoute"
+	KindGRPCRoute = "GRPCRoute"
+)
+
+// Resources of route kinds
+var routeResources = map[string]string{
+	KindHTTPRoute: "httproutes",
+	KindGRPCRoute: "grpcroutes",
+}
+
+// ServiceRef is a namespaced reference to a Service
+type ServiceRef struct {
+	Namespace string
+	Name      string
+}
+
+// ParentRef is a Gateway (or its listener) the route is attached to
+type ParentRef struct {
+	Kind        string
+	Namespace   string
+	Name        string
+	SectionName string
+}
+
+// RouteRule is a rule of HTTPRoute or GRPCRoute
+type RouteRule struct {
+	// Human readable matches, e.g. "PathPrefix /api method=GET" or "grpc.health.v1.Health/Check"
+	Matches     []string
+	BackendRefs []BackendRef
+}
+
+// BackendRef is a backend of a route rule
+type BackendRef struct {
+	Kind      string // Service if empty
+	Namespace string
+	Name      string
+	Port      int64
+}
+
+// Route is an API version independent view of HTTPRoute and GRPCRoute objects
+type Route struct {
+	Kind       string
+	Namespace  string
+	Name       string
+	ParentRefs []ParentRef
+	Hostnames  []string
+	Rules      []RouteRule
+}
+
+// GatewayAPI reads Gateway API routes using the dynamic client, so Gateway API CRDs are optional
+type GatewayAPI struct {
+	dClient dynamic.Interface
+	// Served route kinds
+	routeGVRs map[string]schema.GroupVersionResource
+}
+
+// NewGatewayAPI discovers served route kinds. Nothing is served if Gateway API CRDs aren't installed.
+func NewGatewayAPI(kClient *kubernetes.Clientset, dClient dynamic.Interface) (*GatewayAPI, error) {
+	gw := &GatewayAPI{dClient: dClient, routeGVRs: map[string]schema.GroupVersionResource{}}
+
+	for kind, resource := range routeResources {
+		gvr, found, err := GetServedResource(kClient, gatewayGroupVersions, resource)
+		if err != nil {
+			return nil, err
+		}
+		if found {
+			klog.V(3).Infof("Using Gateway API %v for %v", gvr.GroupVersion(), kind)
+			gw.routeGVRs[kind] = gvr
+		}
+	}
+
+	return gw, nil
+}
+
+// Served checks whether route kind is served by the cluster
+func (gw *GatewayAPI) Served(kind string) bool {
+	_, ok := gw.routeGVRs[kind]
+	return ok
+}
+
+// GetRoute returns route of given kind by name
+func (gw *GatewayAPI) GetRoute(kind, namespace, name string) (*Route, error) {
+	gvr, ok := gw.routeGVRs[kind]
+	if !ok {
+		return nil, fmt.Errorf("%v is not served by the cluster", kind)
+	}
+
+	obj, err := gw.dClient.Resource(gvr).Namespace(namespace).Get(name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	return routeFromUnstructured(kind, obj), nil
+}
+
+// ListRoutes returns routes of all served kinds. Empty namespace means all namespaces.
+func (gw *GatewayAPI) ListRoutes(namespace string) ([]Route, error) {
+	var routes []Route
+	for kind, gvr := range gw.routeGVRs {
+		list, err := gw.dClient.Resource(gvr).Namespace(namespace).List(metav1.ListOptions{})
+		if err != nil {
+			return nil, err
+		}
+		for i := range list.Items {
+			routes = append(routes, *routeFromUnstructured(kind, &list.Items[i]))
+		}
+	}
+
+	return routes, nil
+}
+
+// BackendServices returns Services referenced by the rule with given index, negative index means all rules
+func (route *Route) BackendServices(rule int) []ServiceRef {
+	var refs []ServiceRef
+	seen := map[ServiceRef]bool{}
+	for i, r := range route.Rules {
+		if rule >= 0 && i != rule {
+			continue
+		}
+		for _, backend := range r.BackendRefs {
+			if backend.Kind != "" && backend.Kind != "Service" {
+				continue
+			}
+			ref := ServiceRef{Namespace: backend.Namespace, Name: backend.Name}
+			if !seen[ref] {
+				seen[ref] = true
+				refs = append(refs, ref)
+			}
+		}
+	}
+
+	return refs
+}
+
+// routeFromUnstructured converts HTTPRoute and GRPCRoute objects
+func routeFromUnstructured(kind string, obj *unstructured.Unstructured) *Route {
+	route := &Route{
+		Kind:      kind,
+		Namespace: obj.GetNamespace(),
+		Name:      obj.GetName(),
+	}
+
+	route.Hostnames, _, _ = unstructured.NestedStringSlice(obj.Object, "spec", "hostnames")
+
+	parentRefs, _, _ := unstructured.NestedSlice(obj.Object, "spec", "parentRefs")
+	for _, p := range parentRefs {
+		m, ok := p.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		ref := ParentRef{Kind: "Gateway", Namespace: route.Namespace}
+		if kind, found, _ := unstructured.NestedString(m, "kind"); found {
+			ref.Kind = kind
+		}
+		if ns, found, _ := unstructured.NestedString(m, "namespace"); found {
+			ref.Namespace = ns
+		}
+		ref.Name, _, _ = unstructured.NestedString(m, "name")
+		ref.SectionName, _, _ = unstructured.NestedString(m, "sectionName")
+		route.ParentRefs = append(route.ParentRefs, ref)
+	}
+
+	rules, _, _ := unstructured.NestedSlice(obj.Object, "spec", "rules")
+	for _, r := range rules {
+		ruleMap, ok := r.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		rule := RouteRule{}
+
+		matches, _, _ := unstructured.NestedSlice(ruleMap, "matches")
+		for _, match := range matches {
+			if matchMap, ok := match.(map[string]interface{}); ok {
+				rule.Matches = append(rule.Matches, describeRouteMatch(kind, matchMap))
+			}
+		}
+
+		backendRefs, _, _ := unstructured.NestedSlice(ruleMap, "backendRefs")
+		for _, b := range backendRefs {
+			m, ok := b.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			backend := BackendRef{Namespace: route.Namespace}
+			backend.Kind, _, _ = unstructured.NestedString(m, "kind")
+			backend.Name, _, _ = unstructured.NestedString(m, "name")
+			backend.Port, _, _ = unstructured.NestedInt64(m, "port")
+			if ns, found, _ := unstructured.NestedString(m, "namespace"); found {
+				backend.Namespace = ns
+			}
+			rule.BackendRefs = append(rule.BackendRefs, backend)
+		}
+
+		route.Rules = append(route.Rules, rule)
+	}
+
+	return route
+}
+
+// describeRouteMatch returns human readable HTTPRouteMatch or GRPCRouteMatch
+func describeRouteMatch(kind string, m map[string]interface{}) string {
+	var parts []string
+
+	if kind == KindGRPCRoute {
+		service, _, _ := unstructured.NestedString(m, "method", "service")
+		method, _, _ := unstructured.NestedString(m, "method", "method")
+		if service == "" {
+			service = "*"
+		}
+		if method == "" {
+			method = "*"
+		}
+		parts = append(parts, service+"/"+method)
+	} else {
+		if value, found, _ := unstructured.NestedString(m, "path", "value"); found {
+			pathType, _, _ := unstructured.NestedString(m, "path", "type")
+			if pathType == "" {
+				pathType = "PathPrefix"
+			}
+			parts = append(parts, pathType+" "+value)
+		}
+		if method, found, _ := unstructured.NestedString(m, "method"); found {
+			parts = append(parts, "method="+method)
+		}
+		queryParams, _, _ := unstructured.NestedSlice(m, "queryParams")
+		for _, q := range queryParams {
+			if qm, ok := q.(map[string]interface{}); ok {
+				name, _, _ := unstructured.NestedString(qm, "name")
+				value, _, _ := unstructured.NestedString(qm, "value")
+				parts = append(parts, "query:"+name+"="+value)
+			}
+		}
+	}
+
+	headers, _, _ := unstructured.NestedSlice(m, "headers")
+	for _, h := range headers {
+		if hm, ok := h.(map[string]interface{}); ok {
+			name, _, _ := unstructured.NestedString(hm, "name")
+			value, _, _ := unstructured.NestedString(hm, "value")
+			parts = append(parts, "header:"+name+"="+value)
+		}
+	}
+
+	if len(parts) == 0 {
+		return "*"
+	}
+
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,56 @@
+package ukubernetes
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// HPA is a HorizontalPodAutoscaler with its scale target
+type HPA struct {
+	Namespace       string
+	Name            string
+	Target          Workload
+	MinReplicas     int32
+	MaxReplicas     int32
+	CurrentReplicas int32
+	// Current CPU utilization (percent of requests), nil if unknown
+	CurrentCPU *int32
+}
+
+// ListHPAs returns HorizontalPodAutoscalers. Empty namespace means all namespaces.
+// autoscaling/v1 is used as it is served by all Kubernetes versions.
+func ListHPAs(kClient *kubernetes.Clientset, namespace string) ([]HPA, error) {
+	list, err := kClient.AutoscalingV1().HorizontalPodAutoscalers(namespace).List(metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	hpas := make([]HPA, 0, len(list.Items))
+	for _, item := range list.Items {
+		hpa := HPA{
+			Namespace:       item.Namespace,
+			Name:            item.Name,
+			Target:          Workload{Kind: item.Spec.ScaleTargetRef.Kind, Name: item.Spec.ScaleTargetRef.Name},
+			MinReplicas:     1,
+			MaxReplicas:     item.Spec.MaxReplicas,
+			CurrentReplicas: item.Status.CurrentReplicas,
+			CurrentCPU:      item.Status.CurrentCPUUtilizationPercentage,
+		}
+		if item.Spec.MinReplicas != nil {
+			hpa.MinReplicas = *item.Spec.MinReplicas
+		}
+		hpas = append(hpas, hpa)
+	}
+
+	return hpas, nil
+}
+
+// HPAsByTarget indexes HPAs by "namespace/Kind/name" of their scale targets
+func HPAsByTarget(hpas []HPA) map[string]*HPA {
+	index := make(map[string]*HPA, len(hpas))
+	for i := range hpas {
+		index[hpas[i].Namespace+"/"+hpas[i].Target.Kind+"/"+hpas[i].Target.Name] = &hpas[i]
+	}
+
+	return index
+}
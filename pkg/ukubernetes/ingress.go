@@ -0,0 +1,589 @@
+package ukubernetes
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog"
+)
+
+// Ingress API versions in order of preference
+var ingressGroupVersions = []schema.GroupVersion{
+	{Group: "networking.k8s.io", Version: "v1"},
+	{Group: "networking.k8s.io", Version: "v1beta1"},
+	{Group: "extensions", Version: "v1beta1"},
+}
+
+// IngressClass API versions in order of preference
+var ingressClassGroupVersions = []schema.GroupVersion{
+	{Group: "networking.k8s.io", Version: "v1"},
+	{Group: "networking.k8s.io", Version: "v1beta1"},
+}
+
+const (
+	// Annotation used to select ingress class before IngressClass resource existed
+	ingressClassAnnotation = "kubernetes.io/ingress.class"
+	// Annotation which marks IngressClass as default one
+	defaultIngressClassAnnotation = "ingressclass.kubernetes.io/is-default-class"
+)
+
+// Reasons why an ingress backend can't serve traffic
+const (
+	ReasonServiceNotFound     = "service not found"
+	ReasonServicePortNotFound = "service port not found"
+	ReasonNoEndpoints         = "service has no endpoints"
+)
+
+// IngressBackend describes all endpoints for a given service and port.
+type IngressBackend struct {
+	// Specifies the name of the referenced service.
+	ServiceName string `json:"serviceName" protobuf:"bytes,1,opt,name=serviceName"`
+
+	// Specifies the port of the referenced service.
+	ServicePort intstr.IntOrString `json:"servicePort" protobuf:"bytes,2,opt,name=servicePort"`
+
+	// Resource backend (mutually exclusive with ServiceName)
+	Resource *v1.TypedLocalObjectReference `json:"resource,omitempty"`
+}
+
+// IngressPath is a path of an ingress rule with its backend
+type IngressPath struct {
+	Path string
+	// Exact, Prefix or ImplementationSpecific. Empty on API versions without path types.
+	PathType string
+	Backend  IngressBackend
+}
+
+// Ingress rule. Paths are empty if the rule has no HTTP section.
+type Rule struct {
+	Host  string
+	Paths []IngressPath
+}
+
+// Ingress is an API version independent view of an Ingress object
+type Ingress struct {
+	Name           string
+	Namespace      string
+	ClassName      string // empty if neither spec.ingressClassName nor class annotation is set
+	DefaultBackend *IngressBackend
+	Rules          []Rule
+	TLSSecrets     []string
+}
+
+// IngressClass is an API version independent view of an IngressClass object
+type IngressClass struct {
+	Name       string
+	Controller string
+	IsDefault  bool
+}
+
+// DanglingIngress is an ingress rule (or default backend) whose backend can't serve traffic
+type DanglingIngress struct {
+	Namespace string
+	Ingress   string
+	Host      string // empty for the default backend and host-less rules
+	Path      string
+	Backend   IngressBackend
+	Reason    string
+}
+
+// IngressAPI reads Ingress objects using the API version served by the cluster
+type IngressAPI struct {
+	kClient *kubernetes.Clientset
+	dClient dynamic.Interface
+
+	ingressGVR schema.GroupVersionResource
+	classGVR   schema.GroupVersionResource // empty if IngressClass isn't served
+
+	// Ingress classes to analyze, nil means all
+	classes map[string]bool
+	// Class of ingresses without explicit class
+	defaultClass string
+}
+
+// NewIngressAPI discovers served Ingress and IngressClass API versions
+func NewIngressAPI(kClient *kubernetes.Clientset, dClient dynamic.Interface) (*IngressAPI, error) {
+	ingressGVR, found, err := GetServedResource(kClient, ingressGroupVersions, "ingresses")
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, fmt.Errorf("ingress API is not served by the cluster")
+	}
+	klog.V(3).Infof("Using Ingress API %v", ingressGVR.GroupVersion())
+
+	classGVR, found, err := GetServedResource(kClient, ingressClassGroupVersions, "ingressclasses")
+	if err != nil {
+		return nil, err
+	}
+	if found {
+		klog.V(3).Infof("Using IngressClass API %v", classGVR.GroupVersion())
+	}
+
+	return &IngressAPI{kClient: kClient, dClient: dClient, ingressGVR: ingressGVR, classGVR: classGVR}, nil
+}
+
+// SetScope limits analysis to ingresses of given classes and classes handled by given controllers
+// (e.g. "k8s.io/ingress-nginx"). Empty classes and controllers mean all ingresses.
+func (a *IngressAPI) SetScope(classes []string, controllers []string) error {
+	a.classes = nil
+	a.defaultClass = ""
+
+	ingressClasses, err := a.ListClasses()
+	if err != nil {
+		return err
+	}
+	for _, class := range ingressClasses {
+		if class.IsDefault {
+			a.defaultClass = class.Name
+		}
+	}
+
+	if len(classes) == 0 && len(controllers) == 0 {
+		return nil
+	}
+
+	a.classes = map[string]bool{}
+	for _, class := range classes {
+		a.classes[class] = true
+	}
+	for _, controller := range controllers {
+		for _, class := range ingressClasses {
+			if class.Controller == controller {
+				a.classes[class.Name] = true
+			}
+		}
+	}
+	klog.V(3).Infof("Ingress classes in scope: %v", a.classes)
+
+	return nil
+}
+
+// InScope checks whether ingress belongs to one of the classes set by SetScope
+func (a *IngressAPI) InScope(ing *Ingress) bool {
+	if a.classes == nil {
+		return true
+	}
+	class := ing.ClassName
+	if class == "" {
+		class = a.defaultClass
+	}
+
+	return a.classes[class]
+}
+
+// ListClasses returns IngressClasses of the cluster (nothing if the API isn't served)
+func (a *IngressAPI) ListClasses() ([]IngressClass, error) {
+	if a.classGVR.Resource == "" {
+		return nil, nil
+	}
+	list, err := a.dClient.Resource(a.classGVR).List(metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	classes := make([]IngressClass, 0, len(list.Items))
+	for _, item := range list.Items {
+		controller, _, _ := unstructured.NestedString(item.Object, "spec", "controller")
+		classes = append(classes, IngressClass{
+			Name:       item.GetName(),
+			Controller: controller,
+			IsDefault:  item.GetAnnotations()[defaultIngressClassAnnotation] == "true",
+		})
+	}
+
+	return classes, nil
+}
+
+// Get returns ingress by name
+func (a *IngressAPI) Get(namespace, name string) (*Ingress, error) {
+	obj, err := a.dClient.Resource(a.ingressGVR).Namespace(namespace).Get(name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	return ingressFromUnstructured(obj), nil
+}
+
+// List returns ingresses in scope. Empty namespace means all namespaces.
+func (a *IngressAPI) List(namespace string) ([]Ingress, error) {
+	return a.list(namespace, true)
+}
+
+// ListAll returns ingresses of all classes. Empty namespace means all namespaces.
+func (a *IngressAPI) ListAll(namespace string) ([]Ingress, error) {
+	return a.list(namespace, false)
+}
+
+func (a *IngressAPI) list(namespace string, scoped bool) ([]Ingress, error) {
+	list, err := a.dClient.Resource(a.ingressGVR).Namespace(namespace).List(metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	ingresses := make([]Ingress, 0, len(list.Items))
+	for i := range list.Items {
+		ing := ingressFromUnstructured(&list.Items[i])
+		if scoped && !a.InScope(ing) {
+			continue
+		}
+		ingresses = append(ingresses, *ing)
+	}
+
+	return ingresses, nil
+}
+
+// Ingress path types (networking.k8s.io/v1)
+const (
+	PathTypeExact                  = "Exact"
+	PathTypePrefix                 = "Prefix"
+	PathTypeImplementationSpecific = "ImplementationSpecific"
+)
+
+// ingressMatch is a matched backend with its precedence
+type ingressMatch struct {
+	backend IngressBackend
+	host    int // 2 - exact host, 1 - wildcard host, 0 - rule without host
+	exact   bool
+	pathLen int
+}
+
+// GetIngressBackends returns backends of all rules matching host and path, most specific first:
+// exact hosts precede wildcard hosts and host-less rules, Exact paths precede prefixes and longer
+// paths precede shorter ones. The default backend is returned if no rule matches.
+func (ing *Ingress) GetIngressBackends(host, path string) []IngressBackend {
+	var matches []ingressMatch
+	for _, rule := range ing.Rules {
+		hostPrecedence, ok := matchIngressHost(rule.Host, host)
+		if !ok {
+			continue
+		}
+		for _, tPath := range rule.Paths {
+			exact, ok := matchIngressPath(tPath, path)
+			if !ok {
+				continue
+			}
+			matches = append(matches, ingressMatch{
+				backend: tPath.Backend,
+				host:    hostPrecedence,
+				exact:   exact,
+				pathLen: len(strings.TrimSuffix(tPath.Path, "/")),
+			})
+		}
+	}
+
+	if len(matches) == 0 {
+		if ing.DefaultBackend != nil {
+			return []IngressBackend{*ing.DefaultBackend}
+		}
+		return nil
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].host != matches[j].host {
+			return matches[i].host > matches[j].host
+		}
+		if matches[i].exact != matches[j].exact {
+			return matches[i].exact
+		}
+		return matches[i].pathLen > matches[j].pathLen
+	})
+
+	// Several paths may point at the same backend
+	var backends []IngressBackend
+	seen := map[string]bool{}
+	for _, m := range matches {
+		key := m.backend.String()
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		backends = append(backends, m.backend)
+	}
+
+	return backends
+}
+
+// IdleBackend returns the backend serving requests to host and path: the most specific matching rule or the
+// default backend. Less specific rules (e.g. catch-all "/" next to idle "/api") serve other requests too, so they
+// aren't idle because host and path are.
+func (ing *Ingress) IdleBackend(host, path string) (IngressBackend, bool) {
+	backends := ing.GetIngressBackends(host, path)
+	if len(backends) == 0 {
+		return IngressBackend{}, false
+	}
+
+	return backends[0], true
+}
+
+// String returns "service:port" or "Kind/name" for resource backends
+func (backend IngressBackend) String() string {
+	if backend.Resource != nil {
+		return backend.Resource.Kind + "/" + backend.Resource.Name
+	}
+
+	return backend.ServiceName + ":" + backend.ServicePort.String()
+}
+
+// matchIngressHost matches host against rule's host. Rules without host match any host,
+// wildcard "*.foo.com" matches exactly one DNS label ("bar.foo.com", not "foo.com" or "baz.bar.foo.com").
+func matchIngressHost(ruleHost, host string) (precedence int, ok bool) {
+	ruleHost = strings.ToLower(ruleHost)
+	host = strings.ToLower(host)
+
+	switch {
+	case ruleHost == "":
+		return 0, true
+	case strings.HasPrefix(ruleHost, "*."):
+		dot := strings.Index(host, ".")
+		if dot <= 0 {
+			return 0, false
+		}
+		return 1, host[dot:] == ruleHost[1:]
+	default:
+		return 2, ruleHost == host
+	}
+}
+
+// matchIngressPath matches path against ingress path. Prefix paths are matched element-wise
+// ("/foo" matches "/foo" and "/foo/bar", not "/foobar"). Controllers interpret ImplementationSpecific
+// paths on their own (regexps, globs), so they are matched as the exact string first, then as prefixes.
+// Paths without type (pre-v1 APIs) are treated as ImplementationSpecific.
+func matchIngressPath(ingressPath IngressPath, path string) (exact bool, ok bool) {
+	switch ingressPath.PathType {
+	case PathTypeExact:
+		return true, ingressPath.Path == path
+	case PathTypePrefix:
+		return false, matchPathPrefix(ingressPath.Path, path)
+	default:
+		if ingressPath.Path == path {
+			return true, true
+		}
+		return false, matchPathPrefix(ingressPath.Path, path)
+	}
+}
+
+// matchPathPrefix checks whether prefix is an element-wise prefix of path, ignoring trailing slashes
+func matchPathPrefix(prefix, path string) bool {
+	prefix = strings.TrimSuffix(prefix, "/")
+	if prefix == "" {
+		return true
+	}
+	path = strings.TrimSuffix(path, "/")
+
+	return path == prefix || strings.HasPrefix(path, prefix+"/")
+}
+
+// GetDanglingIngresses returns ingress rules pointing at missing Services, missing service ports
+// or Services without ready endpoints. Empty namespace means all namespaces.
+func (a *IngressAPI) GetDanglingIngresses(namespace string) ([]DanglingIngress, error) {
+	ingresses, err := a.List(namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	var dangling []DanglingIngress
+
+	// Many rules usually point at the same Service, check each backend only once
+	checked := map[string]string{}
+
+	check := func(ing *Ingress, host, path string, backend *IngressBackend) error {
+		// Resource backends are served by controller specific objects, can't check them
+		if backend == nil || backend.ServiceName == "" {
+			return nil
+		}
+		key := ing.Namespace + "/" + backend.String()
+		reason, ok := checked[key]
+		if !ok {
+			reason, err = checkIngressBackend(a.kClient, ing.Namespace, *backend)
+			if err != nil {
+				return err
+			}
+			checked[key] = reason
+		}
+		if reason != "" {
+			dangling = append(dangling, DanglingIngress{
+				Namespace: ing.Namespace,
+				Ingress:   ing.Name,
+				Host:      host,
+				Path:      path,
+				Backend:   *backend,
+				Reason:    reason,
+			})
+		}
+		return nil
+	}
+
+	for i := range ingresses {
+		ing := &ingresses[i]
+		if err := check(ing, "", "", ing.DefaultBackend); err != nil {
+			return nil, err
+		}
+		for _, rule := range ing.Rules {
+			for _, tPath := range rule.Paths {
+				backend := tPath.Backend
+				if err := check(ing, rule.Host, tPath.Path, &backend); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	return dangling, nil
+}
+
+// checkIngressBackend returns the reason why backend can't serve traffic or empty string if it can
+func checkIngressBackend(kClient *kubernetes.Clientset, namespace string, backend IngressBackend) (string, error) {
+	svc, err := kClient.CoreV1().Services(namespace).Get(backend.ServiceName, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return ReasonServiceNotFound, nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	// ExternalName services have neither ports nor endpoints
+	if svc.Spec.Type == v1.ServiceTypeExternalName {
+		return "", nil
+	}
+
+	if !serviceHasPort(svc, backend.ServicePort) {
+		return ReasonServicePortNotFound, nil
+	}
+
+	endpoints, err := kClient.CoreV1().Endpoints(namespace).Get(backend.ServiceName, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return ReasonNoEndpoints, nil
+	}
+	if err != nil {
+		return "", err
+	}
+	for _, subset := range endpoints.Subsets {
+		if len(subset.Addresses) > 0 {
+			return "", nil
+		}
+	}
+
+	return ReasonNoEndpoints, nil
+}
+
+// serviceHasPort checks whether port (number or name) is exposed by the service
+func serviceHasPort(svc *v1.Service, port intstr.IntOrString) bool {
+	for _, svcPort := range svc.Spec.Ports {
+		if port.Type == intstr.Int && svcPort.Port == port.IntVal {
+			return true
+		}
+		if port.Type == intstr.String && svcPort.Name == port.StrVal {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ingressFromUnstructured converts networking.k8s.io/v1, networking.k8s.io/v1beta1 and
+// extensions/v1beta1 Ingress objects
+func ingressFromUnstructured(obj *unstructured.Unstructured) *Ingress {
+	ing := &Ingress{
+		Name:      obj.GetName(),
+		Namespace: obj.GetNamespace(),
+	}
+
+	ing.ClassName, _, _ = unstructured.NestedString(obj.Object, "spec", "ingressClassName")
+	if ing.ClassName == "" {
+		ing.ClassName = obj.GetAnnotations()[ingressClassAnnotation]
+	}
+
+	// spec.defaultBackend in v1, spec.backend in v1beta1
+	for _, field := range []string{"defaultBackend", "backend"} {
+		if m, found, _ := unstructured.NestedMap(obj.Object, "spec", field); found {
+			backend := backendFromUnstructured(m)
+			ing.DefaultBackend = &backend
+			break
+		}
+	}
+
+	tls, _, _ := unstructured.NestedSlice(obj.Object, "spec", "tls")
+	for _, t := range tls {
+		if tlsMap, ok := t.(map[string]interface{}); ok {
+			if secret, found, _ := unstructured.NestedString(tlsMap, "secretName"); found && secret != "" {
+				ing.TLSSecrets = append(ing.TLSSecrets, secret)
+			}
+		}
+	}
+
+	rules, _, _ := unstructured.NestedSlice(obj.Object, "spec", "rules")
+	for _, r := range rules {
+		ruleMap, ok := r.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		rule := Rule{}
+		rule.Host, _, _ = unstructured.NestedString(ruleMap, "host")
+
+		paths, _, _ := unstructured.NestedSlice(ruleMap, "http", "paths")
+		for _, p := range paths {
+			pathMap, ok := p.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			path := IngressPath{}
+			path.Path, _, _ = unstructured.NestedString(pathMap, "path")
+			path.PathType, _, _ = unstructured.NestedString(pathMap, "pathType")
+			if m, found, _ := unstructured.NestedMap(pathMap, "backend"); found {
+				path.Backend = backendFromUnstructured(m)
+			}
+			rule.Paths = append(rule.Paths, path)
+		}
+		ing.Rules = append(ing.Rules, rule)
+	}
+
+	return ing
+}
+
+// backendFromUnstructured converts both v1 (`service.name`, `service.port.name/number`)
+// and v1beta1 (`serviceName`, `servicePort`) backend shapes
+func backendFromUnstructured(m map[string]interface{}) IngressBackend {
+	backend := IngressBackend{}
+
+	if name, found, _ := unstructured.NestedString(m, "service", "name"); found {
+		backend.ServiceName = name
+		if portName, found, _ := unstructured.NestedString(m, "service", "port", "name"); found {
+			backend.ServicePort = intstr.FromString(portName)
+		}
+		if number, found, _ := unstructured.NestedInt64(m, "service", "port", "number"); found {
+			backend.ServicePort = intstr.FromInt(int(number))
+		}
+	} else {
+		backend.ServiceName, _, _ = unstructured.NestedString(m, "serviceName")
+		if port, found, _ := unstructured.NestedFieldNoCopy(m, "servicePort"); found {
+			switch p := port.(type) {
+			case int64:
+				backend.ServicePort = intstr.FromInt(int(p))
+			case string:
+				backend.ServicePort = intstr.Parse(p)
+			}
+		}
+	}
+
+	if resource, found, _ := unstructured.NestedStringMap(m, "resource"); found {
+		backend.Resource = &v1.TypedLocalObjectReference{
+			Kind: resource["kind"],
+			Name: resource["name"],
+		}
+		if group, ok := resource["apiGroup"]; ok {
+			backend.Resource.APIGroup = &group
+		}
+	}
+
+	return backend
+}
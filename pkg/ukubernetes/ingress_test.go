@@ -0,0 +1,230 @@
+package ukubernetes
+
+import (
+	"reflect"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func backend(name string) IngressBackend {
+	return IngressBackend{ServiceName: name, ServicePort: intstr.FromInt(80)}
+}
+
+func TestGetIngressBackends(t *testing.T) {
+	defaultBackend := backend("default")
+
+	tests := []struct {
+		name     string
+		ingress  Ingress
+		host     string
+		path     string
+		expected []IngressBackend
+	}{
+		{
+			name: "exact host and path",
+			ingress: Ingress{Rules: []Rule{
+				{Host: "foo.com", Paths: []IngressPath{{Path: "/api", PathType: PathTypeExact, Backend: backend("api")}}},
+			}},
+			host:     "foo.com",
+			path:     "/api",
+			expected: []IngressBackend{backend("api")},
+		},
+		{
+			name: "host is case insensitive",
+			ingress: Ingress{Rules: []Rule{
+				{Host: "Foo.com", Paths: []IngressPath{{Path: "/", PathType: PathTypePrefix, Backend: backend("web")}}},
+			}},
+			host:     "foo.COM",
+			path:     "/",
+			expected: []IngressBackend{backend("web")},
+		},
+		{
+			name: "other host doesn't match",
+			ingress: Ingress{Rules: []Rule{
+				{Host: "foo.com", Paths: []IngressPath{{Path: "/", PathType: PathTypePrefix, Backend: backend("web")}}},
+			}},
+			host:     "bar.com",
+			path:     "/",
+			expected: nil,
+		},
+		{
+			name: "rule without host matches any host",
+			ingress: Ingress{Rules: []Rule{
+				{Paths: []IngressPath{{Path: "/", PathType: PathTypePrefix, Backend: backend("web")}}},
+			}},
+			host:     "bar.com",
+			path:     "/index.html",
+			expected: []IngressBackend{backend("web")},
+		},
+		{
+			name: "wildcard host matches single label",
+			ingress: Ingress{Rules: []Rule{
+				{Host: "*.foo.com", Paths: []IngressPath{{Path: "/", PathType: PathTypePrefix, Backend: backend("web")}}},
+			}},
+			host:     "bar.foo.com",
+			path:     "/",
+			expected: []IngressBackend{backend("web")},
+		},
+		{
+			name: "wildcard host doesn't match several labels",
+			ingress: Ingress{Rules: []Rule{
+				{Host: "*.foo.com", Paths: []IngressPath{{Path: "/", PathType: PathTypePrefix, Backend: backend("web")}}},
+			}},
+			host:     "baz.bar.foo.com",
+			path:     "/",
+			expected: nil,
+		},
+		{
+			name: "wildcard host doesn't match bare domain",
+			ingress: Ingress{Rules: []Rule{
+				{Host: "*.foo.com", Paths: []IngressPath{{Path: "/", PathType: PathTypePrefix, Backend: backend("web")}}},
+			}},
+			host:     "foo.com",
+			path:     "/",
+			expected: nil,
+		},
+		{
+			name: "rule without HTTP section",
+			ingress: Ingress{
+				Rules:          []Rule{{Host: "foo.com"}},
+				DefaultBackend: &defaultBackend,
+			},
+			host:     "foo.com",
+			path:     "/",
+			expected: []IngressBackend{defaultBackend},
+		},
+		{
+			name: "prefix matches path elements",
+			ingress: Ingress{Rules: []Rule{
+				{Host: "foo.com", Paths: []IngressPath{{Path: "/api/", PathType: PathTypePrefix, Backend: backend("api")}}},
+			}},
+			host:     "foo.com",
+			path:     "/api/v1",
+			expected: []IngressBackend{backend("api")},
+		},
+		{
+			name: "prefix doesn't match partial path element",
+			ingress: Ingress{Rules: []Rule{
+				{Host: "foo.com", Paths: []IngressPath{{Path: "/api", PathType: PathTypePrefix, Backend: backend("api")}}},
+			}},
+			host:     "foo.com",
+			path:     "/apiv1",
+			expected: nil,
+		},
+		{
+			name: "exact doesn't match sub path",
+			ingress: Ingress{Rules: []Rule{
+				{Host: "foo.com", Paths: []IngressPath{{Path: "/api", PathType: PathTypeExact, Backend: backend("api")}}},
+			}},
+			host:     "foo.com",
+			path:     "/api/v1",
+			expected: nil,
+		},
+		{
+			name: "implementation specific matches same string",
+			ingress: Ingress{Rules: []Rule{
+				{Host: "foo.com", Paths: []IngressPath{
+					{Path: "/api(/|$)(.*)", PathType: PathTypeImplementationSpecific, Backend: backend("api")},
+				}},
+			}},
+			host:     "foo.com",
+			path:     "/api(/|$)(.*)",
+			expected: []IngressBackend{backend("api")},
+		},
+		{
+			name: "path without type is matched as prefix",
+			ingress: Ingress{Rules: []Rule{
+				{Host: "foo.com", Paths: []IngressPath{{Path: "", Backend: backend("web")}}},
+			}},
+			host:     "foo.com",
+			path:     "/anything",
+			expected: []IngressBackend{backend("web")},
+		},
+		{
+			name: "all matches are returned, most specific first",
+			ingress: Ingress{Rules: []Rule{
+				{Paths: []IngressPath{{Path: "/", PathType: PathTypePrefix, Backend: backend("catch-all")}}},
+				{Host: "*.foo.com", Paths: []IngressPath{{Path: "/", PathType: PathTypePrefix, Backend: backend("wildcard")}}},
+				{Host: "bar.foo.com", Paths: []IngressPath{
+					{Path: "/", PathType: PathTypePrefix, Backend: backend("root")},
+					{Path: "/api", PathType: PathTypePrefix, Backend: backend("api-prefix")},
+					{Path: "/api/v1", PathType: PathTypeExact, Backend: backend("api-exact")},
+				}},
+			}},
+			host: "bar.foo.com",
+			path: "/api/v1",
+			expected: []IngressBackend{backend("api-exact"), backend("api-prefix"), backend("root"),
+				backend("wildcard"), backend("catch-all")},
+		},
+		{
+			name: "same backend is returned once",
+			ingress: Ingress{Rules: []Rule{
+				{Host: "foo.com", Paths: []IngressPath{
+					{Path: "/a", PathType: PathTypePrefix, Backend: backend("web")},
+					{Path: "/", PathType: PathTypePrefix, Backend: backend("web")},
+				}},
+			}},
+			host:     "foo.com",
+			path:     "/a",
+			expected: []IngressBackend{backend("web")},
+		},
+		{
+			name: "default backend when no rule matches",
+			ingress: Ingress{
+				Rules: []Rule{
+					{Host: "foo.com", Paths: []IngressPath{{Path: "/", PathType: PathTypePrefix, Backend: backend("web")}}},
+				},
+				DefaultBackend: &defaultBackend,
+			},
+			host:     "bar.com",
+			path:     "/",
+			expected: []IngressBackend{defaultBackend},
+		},
+		{
+			name: "default backend is not returned if a rule matches",
+			ingress: Ingress{
+				Rules: []Rule{
+					{Host: "foo.com", Paths: []IngressPath{{Path: "/", PathType: PathTypePrefix, Backend: backend("web")}}},
+				},
+				DefaultBackend: &defaultBackend,
+			},
+			host:     "foo.com",
+			path:     "/",
+			expected: []IngressBackend{backend("web")},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.ingress.GetIngressBackends(tt.host, tt.path)
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("GetIngressBackends(%q, %q) = %v, expected %v", tt.host, tt.path, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestIdleBackend(t *testing.T) {
+	ingress := Ingress{Rules: []Rule{
+		{Host: "foo.com", Paths: []IngressPath{
+			{Path: "/", PathType: PathTypePrefix, Backend: backend("web")},
+			{Path: "/api", PathType: PathTypePrefix, Backend: backend("api")},
+		}},
+		{Host: "*.foo.com", Paths: []IngressPath{{Path: "/", PathType: PathTypePrefix, Backend: backend("wildcard")}}},
+		{Paths: []IngressPath{{Path: "/", PathType: PathTypePrefix, Backend: backend("any")}}},
+	}}
+
+	// Busy "/" isn't flagged because of idle "/api"
+	got, ok := ingress.IdleBackend("foo.com", "/api")
+	if !ok || !reflect.DeepEqual(got, backend("api")) {
+		t.Errorf("IdleBackend(foo.com, /api) = %v, %v, expected api", got, ok)
+	}
+	got, ok = ingress.IdleBackend("bar.foo.com", "/api")
+	if !ok || !reflect.DeepEqual(got, backend("wildcard")) {
+		t.Errorf("IdleBackend(bar.foo.com, /api) = %v, %v, expected wildcard", got, ok)
+	}
+	if got, ok = (&Ingress{}).IdleBackend("foo.com", "/"); ok {
+		t.Errorf("ingress without rules has no backend, got %v", got)
+	}
+}
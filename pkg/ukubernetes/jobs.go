@@ -0,0 +1,213 @@
+package ukubernetes
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Reasons why a Job or CronJob is considered stale
+const (
+	ReasonJobFinished        = "finished long ago"
+	ReasonCronJobSuspended   = "suspended long ago"
+	ReasonCronJobAlwaysFails = "last runs failed"
+)
+
+// StaleJob is a finished Job or a suspended/failing CronJob
+type StaleJob struct {
+	Kind      string
+	Namespace string
+	Name      string
+	Reason    string
+	// Since the job finished or the CronJob was scheduled last time
+	Age time.Duration
+	// Pods which still exist and requests of those not finished yet
+	Pods   int
+	CPU    int64 // milli
+	Memory int64 // bytes
+	// Consecutive failed runs of a CronJob among Jobs it keeps (spec.failedJobsHistoryLimit, 1 by default)
+	FailedRuns int
+	// Since the CronJob was created if it never succeeded (known from batch/v1 only), zero otherwise
+	NeverSucceededFor time.Duration
+}
+
+// GetStaleJobs returns Jobs (not managed by CronJobs) finished more than finishedAge ago and without TTL,
+// CronJobs suspended and not scheduled for more than suspendedAge, and CronJobs whose last failedRuns runs
+// all failed (see RunsFailed). Empty namespace means all namespaces.
+func GetStaleJobs(kClient *kubernetes.Clientset, dClient dynamic.Interface, namespace string,
+	finishedAge, suspendedAge time.Duration, failedRuns int) ([]StaleJob, error) {
+
+	jobs, err := kClient.BatchV1().Jobs(namespace).List(metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	pods, err := kClient.CoreV1().Pods(namespace).List(metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	// Pods of each job ("namespace/job")
+	jobPods := map[string][]*v1.Pod{}
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if ref := metav1.GetControllerOf(pod); ref != nil && ref.Kind == KindJob {
+			key := pod.Namespace + "/" + ref.Name
+			jobPods[key] = append(jobPods[key], pod)
+		}
+	}
+
+	// Jobs of each CronJob ("namespace/cronjob")
+	cronJobJobs := map[string][]*batchv1.Job{}
+
+	var stale []StaleJob
+	for i := range jobs.Items {
+		job := &jobs.Items[i]
+		if ref := metav1.GetControllerOf(job); ref != nil && ref.Kind == KindCronJob {
+			key := job.Namespace + "/" + ref.Name
+			cronJobJobs[key] = append(cronJobJobs[key], job)
+			continue
+		}
+
+		// Jobs with TTL are deleted by Kubernetes
+		if job.Spec.TTLSecondsAfterFinished != nil {
+			continue
+		}
+		finished, _, at := jobFinished(job)
+		if !finished || time.Since(at) < finishedAge {
+			continue
+		}
+
+		s := StaleJob{
+			Kind:      KindJob,
+			Namespace: job.Namespace,
+			Name:      job.Name,
+			Reason:    ReasonJobFinished,
+			Age:       time.Since(at),
+		}
+		s.addPods(jobPods[job.Namespace+"/"+job.Name])
+		stale = append(stale, s)
+	}
+
+	cronJobs, err := ListCronJobs(kClient, dClient, namespace)
+	if err != nil {
+		return nil, err
+	}
+	for _, cronJob := range cronJobs {
+		lastSchedule := cronJob.CreationTimestamp.Time
+		if cronJob.Status.LastScheduleTime != nil {
+			lastSchedule = cronJob.Status.LastScheduleTime.Time
+		}
+		key := cronJob.Namespace + "/" + cronJob.Name
+
+		s := StaleJob{
+			Kind:      KindCronJob,
+			Namespace: cronJob.Namespace,
+			Name:      cronJob.Name,
+			Age:       time.Since(lastSchedule),
+		}
+
+		switch {
+		case cronJob.Spec.Suspend != nil && *cronJob.Spec.Suspend && s.Age >= suspendedAge:
+			s.Reason = ReasonCronJobSuspended
+		case failedRuns > 0:
+			s.FailedRuns = lastRunsFailed(cronJobJobs[key])
+			if cronJob.SuccessReported && cronJob.LastSuccessfulTime == nil && s.FailedRuns > 0 {
+				s.NeverSucceededFor = time.Since(cronJob.CreationTimestamp.Time)
+			}
+			if !s.RunsFailed(failedRuns, finishedAge) {
+				continue
+			}
+			s.Reason = fmt.Sprintf("%v: %v", ReasonCronJobAlwaysFails, s.FailedRuns)
+			if s.NeverSucceededFor > 0 {
+				s.Reason += fmt.Sprintf(" (never succeeded for %vd)", int(s.NeverSucceededFor.Hours()/24))
+			}
+		default:
+			continue
+		}
+
+		for _, job := range cronJobJobs[key] {
+			s.addPods(jobPods[job.Namespace+"/"+job.Name])
+		}
+		stale = append(stale, s)
+	}
+
+	return stale, nil
+}
+
+// RunsFailed checks whether the last runs of the CronJob all failed. Only failed Jobs kept by
+// failedJobsHistoryLimit can be counted, so CronJobs keeping less are reported only if they never succeeded
+// for age (a single failed run of a new CronJob isn't enough).
+func (s *StaleJob) RunsFailed(runs int, age time.Duration) bool {
+	return runs > 0 && (s.FailedRuns >= runs || s.NeverSucceededFor > 0 && s.NeverSucceededFor >= age)
+}
+
+// Command returns kubectl command which cleans the job up
+func (s *StaleJob) Command() string {
+	if s.Kind == KindCronJob && s.Reason != ReasonCronJobSuspended {
+		return fmt.Sprintf(`kubectl -n %v patch cronjob %v -p '{"spec":{"suspend":true}}'`, s.Namespace, s.Name)
+	}
+
+	return fmt.Sprintf("kubectl -n %v delete %v %v", s.Namespace, map[string]string{
+		KindJob:     "job",
+		KindCronJob: "cronjob",
+	}[s.Kind], s.Name)
+}
+
+// addPods counts pods and requests of pods which are not finished yet
+func (s *StaleJob) addPods(pods []*v1.Pod) {
+	for _, pod := range pods {
+		s.Pods++
+		if pod.Status.Phase == v1.PodSucceeded || pod.Status.Phase == v1.PodFailed {
+			continue
+		}
+		cpu, mem := PodRequests(pod)
+		s.CPU += cpu
+		s.Memory += mem
+	}
+}
+
+// jobFinished returns whether job is complete or failed and when it happened
+func jobFinished(job *batchv1.Job) (finished bool, failed bool, at time.Time) {
+	for _, condition := range job.Status.Conditions {
+		if condition.Status != v1.ConditionTrue {
+			continue
+		}
+		switch condition.Type {
+		case batchv1.JobComplete:
+			return true, false, condition.LastTransitionTime.Time
+		case batchv1.JobFailed:
+			return true, true, condition.LastTransitionTime.Time
+		}
+	}
+
+	return false, false, time.Time{}
+}
+
+// lastRunsFailed returns number of the last finished jobs which all failed
+func lastRunsFailed(jobs []*batchv1.Job) int {
+	var finished []*batchv1.Job
+	for _, job := range jobs {
+		if ok, _, _ := jobFinished(job); ok {
+			finished = append(finished, job)
+		}
+	}
+
+	sort.Slice(finished, func(i, j int) bool {
+		return finished[i].CreationTimestamp.After(finished[j].CreationTimestamp.Time)
+	})
+	failedRuns := 0
+	for _, job := range finished {
+		if _, failed, _ := jobFinished(job); !failed {
+			break
+		}
+		failedRuns++
+	}
+
+	return failedRuns
+}
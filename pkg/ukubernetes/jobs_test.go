@@ -0,0 +1,60 @@
+package ukubernetes
+
+import (
+	"testing"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func finishedJob(created time.Time, failed bool) *batchv1.Job {
+	condition := batchv1.JobComplete
+	if failed {
+		condition = batchv1.JobFailed
+	}
+
+	return &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{CreationTimestamp: metav1.NewTime(created)},
+		Status: batchv1.JobStatus{Conditions: []batchv1.JobCondition{
+			{Type: condition, Status: v1.ConditionTrue, LastTransitionTime: metav1.NewTime(created)},
+		}},
+	}
+}
+
+func TestLastRunsFailed(t *testing.T) {
+	now := time.Now()
+	jobs := []*batchv1.Job{
+		finishedJob(now.Add(-3*time.Hour), false),
+		finishedJob(now.Add(-time.Hour), true),
+		finishedJob(now.Add(-2*time.Hour), true),
+		// Running
+		{ObjectMeta: metav1.ObjectMeta{CreationTimestamp: metav1.NewTime(now)}},
+	}
+	if runs := lastRunsFailed(jobs); runs != 2 {
+		t.Errorf("expected 2 failed runs, got %v", runs)
+	}
+}
+
+func TestRunsFailed(t *testing.T) {
+	week := 7 * 24 * time.Hour
+	// CronJob with default failedJobsHistoryLimit keeps a single failed Job
+	s := StaleJob{FailedRuns: 1}
+	if s.RunsFailed(3, week) {
+		t.Error("a single failed run isn't enough")
+	}
+	s.NeverSucceededFor = time.Hour
+	if s.RunsFailed(3, week) {
+		t.Error("new CronJob which never succeeded yet shouldn't be reported")
+	}
+	s.NeverSucceededFor = 2 * week
+	if !s.RunsFailed(3, week) {
+		t.Error("CronJob which never succeeded for long should be reported")
+	}
+
+	s = StaleJob{FailedRuns: 2}
+	if s.RunsFailed(3, week) || !s.RunsFailed(2, week) || s.RunsFailed(0, week) {
+		t.Errorf("unexpected failed runs of %+v", s)
+	}
+}
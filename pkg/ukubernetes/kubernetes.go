@@ -1,68 +1,57 @@
 package ukubernetes
 
 import (
-	"fmt"
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
-	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
 
+	"fmt"
 	"k8s.io/client-go/kubernetes"
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
 	"k8s.io/klog"
 	"os"
-	"path/filepath"
+	"strings"
 	"time"
 )
 
-// Ingress rule
-type Rule struct {
-	Host  string
-	Paths []string
-}
-
-// Ingress
-type Ingress struct {
-	Name      string
-	Namespace string
-	Rules     []Rule
-}
-
-// IngressBackend describes all endpoints for a given service and port.
-type IngressBackend struct {
-	// Specifies the name of the referenced service.
-	ServiceName string `json:"serviceName" protobuf:"bytes,1,opt,name=serviceName"`
-
-	// Specifies the port of the referenced service.
-	ServicePort intstr.IntOrString `json:"servicePort" protobuf:"bytes,2,opt,name=servicePort"`
-}
-
-// GetConfig returns k8s Config struct
-func GetConfig(runOutsideCluster bool) (*rest.Config, error) {
-
-	kubeConfigLocation := ""
-	var config *rest.Config
-	var err error
-	if runOutsideCluster {
-		homeDir := os.Getenv("HOME")
-		kubeConfigLocation = filepath.Join(homeDir, ".kube", "config")
-		klog.V(1).Infof("Kubernetes config Location: %v\n", kubeConfigLocation)
-		// Use the current context in kubeconfig
-		config, err = clientcmd.BuildConfigFromFlags("", kubeConfigLocation)
-		if err != nil {
-			return nil, err
-		}
-	} else {
-		config, err = rest.InClusterConfig()
+// GetConfig returns k8s Config struct. Outside of the cluster kubeconfig is loaded by client-go loading rules:
+// explicit path, $KUBECONFIG, then $HOME/.kube/config; empty context means the current one. Explicit kubeconfig,
+// $KUBECONFIG or context imply running outside of the cluster.
+func GetConfig(runOutsideCluster bool, kubeconfig, context string) (*rest.Config, error) {
+	if !runOutsideCluster && kubeconfig == "" && context == "" &&
+		os.Getenv(clientcmd.RecommendedConfigPathEnvVar) == "" {
+		config, err := rest.InClusterConfig()
 		if err != nil {
 			return nil, err
 		}
 		klog.V(1).Infof("Running inside Kubernetes cluster")
+
+		return config, nil
+	}
+
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	rules.ExplicitPath = kubeconfig
+	overrides := &clientcmd.ConfigOverrides{CurrentContext: context}
+	clientConfig := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, overrides)
+	raw, err := clientConfig.RawConfig()
+	if err != nil {
+		return nil, err
+	}
+	if context == "" {
+		context = raw.CurrentContext
+	}
+	location := strings.Join(rules.GetLoadingPrecedence(), string(os.PathListSeparator))
+	if kubeconfig != "" {
+		location = kubeconfig
 	}
+	klog.V(1).Infof("Kubernetes config Location: %v, context: %v\n", location, context)
 
-	return config, nil
+	return clientConfig.ClientConfig()
 }
 
 // GetKClient returns *kubernetes.Clientset with tested connection
@@ -90,12 +79,39 @@ func GetKClient(restconfig *rest.Config) (*kubernetes.Clientset, error) {
 		}
 	}
 	if !gotNodes {
-		klog.Exit("FATAL: Can't access cluster")
+		return nil, fmt.Errorf("can't access cluster %v", restconfig.Host)
 	}
 
 	return kClient, err
 }
 
+// GetDClient returns dynamic client for resources without typed clients (or not known at build time)
+func GetDClient(restconfig *rest.Config) (dynamic.Interface, error) {
+	return dynamic.NewForConfig(restconfig)
+}
+
+// GetServedResource returns the first of given group versions which serves resource
+func GetServedResource(kClient *kubernetes.Clientset, groupVersions []schema.GroupVersion,
+	resource string) (gvr schema.GroupVersionResource, found bool, err error) {
+
+	for _, gv := range groupVersions {
+		resources, err := kClient.Discovery().ServerResourcesForGroupVersion(gv.String())
+		if errors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return gvr, false, err
+		}
+		for _, r := range resources.APIResources {
+			if r.Name == resource {
+				return gv.WithResource(resource), true, nil
+			}
+		}
+	}
+
+	return gvr, false, nil
+}
+
 // GetMClient returns *metrics.Clientset with tested connection
 //func GetMClient(restconfig *rest.Config) (*metrics.Clientset, error) {
 //	// Setup k8s client
@@ -132,34 +148,6 @@ func GetPodsBySelector(kClient *kubernetes.Clientset, namespace string, selector
 	return pods, nil
 }
 
-// GetIngressBackend returns ingress backend by specific host and path
-func GetIngressBackend(kClient *kubernetes.Clientset, namespace, ingress, host, path string) (backend IngressBackend, err error) {
-
-	ingressStruct, err := kClient.ExtensionsV1beta1().Ingresses(namespace).Get(ingress, metav1.GetOptions{})
-	
-	if err != nil {
-		return backend, err
-	}
-
-	found := false
-	for _, rule := range ingressStruct.Spec.Rules {
-		if rule.Host == host {
-			for _, tPath := range rule.IngressRuleValue.HTTP.Paths {
-				if tPath.Path == path || tPath.Path == "" {
-					backend = IngressBackend(tPath.Backend)
-					found = true
-				}
-			}
-		}
-	}
-
-	if !found {
-		return backend, fmt.Errorf("not found")
-	}
-
-	return backend, nil
-}
-
 // GetPodsCpuReq returns CPU and memory requests
 // 0.100 CPU mean "1/10 of 1 core CPU time".
 // memory units is bytes
@@ -170,6 +158,13 @@ func GetPodRequests(kClient *kubernetes.Clientset, namespace, podName string) (c
 		return 0, 0, err
 	}
 
+	cpu, mem = PodRequests(pod)
+
+	return cpu, mem, nil
+}
+
+// PodRequests returns CPU (milli) and memory (bytes) requests of pod's containers
+func PodRequests(pod *v1.Pod) (cpu int64, mem int64) {
 	var podCpu int64
 	var podMem int64
 	for _, containerName := range pod.Spec.Containers {
@@ -184,39 +179,5 @@ func GetPodRequests(kClient *kubernetes.Clientset, namespace, podName string) (c
 		podMem += containerMem
 	}
 
-	return podCpu, podMem, nil
+	return podCpu, podMem
 }
-
-// GetPodDeployment return's pod's Deployment object
-func GetPodDeployment(kClient *kubernetes.Clientset, namespace, podName string) (deployments []string, err error) {
-	pod, err := kClient.CoreV1().Pods(namespace).Get(podName, metav1.GetOptions{})
-	if err != nil {
-		return nil, err
-	}
-
-	var ds string
-
-	if len(pod.OwnerReferences) > 0 {
-		for _, ref := range pod.OwnerReferences {
-			if ref.Kind != "ReplicaSet" {
-				continue
-			} else {
-				replicaSet, err := kClient.AppsV1().ReplicaSets(namespace).Get(ref.Name, metav1.GetOptions{})
-				if err != nil {
-					return nil, err
-				}
-				if replicaSet.OwnerReferences[0].Kind == "Deployment" {
-					ds = replicaSet.OwnerReferences[0].Name
-				}
-			}
-			if ds == "" {
-				continue
-			}
-			deployments = append(deployments, ds)
-		}
-	} else {
-		return nil, err
-	}
-
-	return deployments, nil
-}
\ No newline at end of file
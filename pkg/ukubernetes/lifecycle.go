@@ -0,0 +1,244 @@
+package ukubernetes
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog"
+)
+
+// StateDeleted is reported when the workload is deleted at the end of its lifecycle
+const StateDeleted = "deleted"
+
+// Reasons of lifecycle events besides scaling down and restoring
+const (
+	EventReasonWarned  = "IdleWarning"
+	EventReasonDeleted = "Deleted"
+)
+
+// Node selector label no node has, keeps DaemonSets without pods until restored
+const ScaledDownNodeSelector = "useless-operator/scaled-down"
+
+// LifecycleConfig is delays between lifecycle stages of idle workloads
+type LifecycleConfig struct {
+	// Since the workload is idle until it's warned
+	WarnAfter time.Duration
+	// Since the warning until the workload is scaled down
+	ScaleDownAfter time.Duration
+	// Since scaling down until the workload is deleted, 0 disables deletion
+	DeleteAfter time.Duration
+}
+
+// Lifecycle moves idle workloads through warned, scaled down and deleted states. States are kept in
+// workloads' marks, so the lifecycle continues after restarts.
+type Lifecycle struct {
+	marker    *Marker
+	recorder  *EventRecorder
+	dClient   dynamic.Interface
+	resources *servedResources
+	config    LifecycleConfig
+}
+
+// NewLifecycle returns lifecycle recording its transitions as events
+func NewLifecycle(kClient *kubernetes.Clientset, dClient dynamic.Interface, marker *Marker, recorder *EventRecorder,
+	config LifecycleConfig) *Lifecycle {
+
+	return &Lifecycle{
+		marker:    marker,
+		recorder:  recorder,
+		dClient:   dClient,
+		resources: newServedResources(kClient),
+		config:    config,
+	}
+}
+
+// Advance moves the marked workload to the next state if its current state lasted long enough.
+// Workloads scaled by HPAs aren't scaled down, HPAs would scale them up again. Returns the resulting state.
+func (l *Lifecycle) Advance(w MarkedWorkload, scaledByHPA bool, now time.Time) (string, error) {
+	marks := w.Marks
+	switch {
+	case marks.State == StateIdle && now.Sub(marks.IdleSince) >= l.config.WarnAfter:
+		if err := l.marker.SetState(w.Namespace, w.Kind, w.Name, StateWarned, now); err != nil {
+			return marks.State, err
+		}
+		l.event(w, v1.EventTypeWarning, EventReasonWarned, "Idle since %v (%v), will be scaled down in %v "+
+			"unless traffic resumes", marks.IdleSince.UTC().Format(time.RFC3339), marks.Reason,
+			l.config.ScaleDownAfter)
+		return StateWarned, nil
+
+	case marks.State == StateWarned && now.Sub(marks.StateSince) >= l.config.ScaleDownAfter:
+		if scaledByHPA {
+			return marks.State, fmt.Errorf("%v %v/%v is scaled by HPA, not scaling down", w.Kind, w.Namespace,
+				w.Name)
+		}
+		if err := l.scaleDown(w); err != nil {
+			return marks.State, err
+		}
+		if err := l.marker.SetState(w.Namespace, w.Kind, w.Name, StateScaledDown, now); err != nil {
+			return marks.State, err
+		}
+		message := "Scaled down after being idle since %v"
+		if l.config.DeleteAfter > 0 {
+			message += fmt.Sprintf(", will be deleted in %v", l.config.DeleteAfter)
+		}
+		l.event(w, v1.EventTypeWarning, EventReasonScaledDown, message, marks.IdleSince.UTC().Format(time.RFC3339))
+		return StateScaledDown, nil
+
+	case marks.State == StateScaledDown && l.config.DeleteAfter > 0 &&
+		now.Sub(marks.StateSince) >= l.config.DeleteAfter:
+
+		gvr, err := l.resources.get(w.Kind)
+		if err != nil {
+			return marks.State, err
+		}
+		// Deleted workload can't be referenced anymore
+		ref := l.reference(w)
+		err = l.dClient.Resource(gvr).Namespace(w.Namespace).Delete(w.Name, &metav1.DeleteOptions{})
+		if err != nil {
+			return marks.State, err
+		}
+		l.eventOn(ref, v1.EventTypeWarning, EventReasonDeleted, "Deleted after being idle since %v",
+			marks.IdleSince.UTC().Format(time.RFC3339))
+		return StateDeleted, nil
+	}
+
+	return marks.State, nil
+}
+
+// Restore scales the workload up to its replicas before scaling down and removes its marks
+func (l *Lifecycle) Restore(namespace, kind, name string) error {
+	gvr, err := l.resources.get(kind)
+	if err != nil {
+		return err
+	}
+	client := l.dClient.Resource(gvr).Namespace(namespace)
+	obj, err := client.Get(name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+
+	var spec map[string]interface{}
+	switch kind {
+	case KindDaemonSet:
+		spec = map[string]interface{}{"template": map[string]interface{}{"spec": map[string]interface{}{
+			"nodeSelector": map[string]interface{}{ScaledDownNodeSelector: nil},
+		}}}
+	case KindCronJob:
+		spec = map[string]interface{}{"suspend": false}
+	default:
+		replicas := int64(1)
+		if value, ok := obj.GetAnnotations()[AnnotationReplicas]; ok {
+			if replicas, err = strconv.ParseInt(value, 10, 32); err != nil {
+				return fmt.Errorf("invalid %v annotation of %v %v/%v: %v", AnnotationReplicas, kind, namespace,
+					name, err)
+			}
+		}
+		spec = map[string]interface{}{"replicas": replicas}
+	}
+	if err := l.patchSpec(client, name, spec, nil); err != nil {
+		return err
+	}
+	if err := l.marker.Unmark(namespace, kind, name); err != nil {
+		return err
+	}
+	l.event(MarkedWorkload{Namespace: namespace, Kind: kind, Name: name}, v1.EventTypeNormal, EventReasonRestored,
+		"Restored")
+
+	return nil
+}
+
+// scaleDown removes pods of the workload, remembering its replicas
+func (l *Lifecycle) scaleDown(w MarkedWorkload) error {
+	gvr, err := l.resources.get(w.Kind)
+	if err != nil {
+		return err
+	}
+	client := l.dClient.Resource(gvr).Namespace(w.Namespace)
+
+	switch w.Kind {
+	case KindDaemonSet:
+		return l.patchSpec(client, w.Name, map[string]interface{}{"template": map[string]interface{}{
+			"spec": map[string]interface{}{
+				"nodeSelector": map[string]interface{}{ScaledDownNodeSelector: "true"},
+			},
+		}}, nil)
+	case KindCronJob:
+		return l.patchSpec(client, w.Name, map[string]interface{}{"suspend": true}, nil)
+	}
+
+	obj, err := client.Get(w.Name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	replicas, found, err := unstructured.NestedInt64(obj.Object, "spec", "replicas")
+	if err != nil {
+		return err
+	}
+	if !found {
+		replicas = 1
+	}
+
+	return l.patchSpec(client, w.Name, map[string]interface{}{"replicas": 0}, map[string]interface{}{
+		AnnotationReplicas: strconv.FormatInt(replicas, 10),
+	})
+}
+
+// patchSpec merges spec and annotations into the object
+func (l *Lifecycle) patchSpec(client dynamic.ResourceInterface, name string, spec,
+	annotations map[string]interface{}) error {
+
+	patch := map[string]interface{}{"spec": spec}
+	if annotations != nil {
+		patch["metadata"] = map[string]interface{}{"annotations": annotations}
+	}
+	data, err := json.Marshal(patch)
+	if err != nil {
+		return err
+	}
+	_, err = client.Patch(name, types.MergePatchType, data, metav1.PatchOptions{})
+
+	return err
+}
+
+// event records lifecycle event on the workload, failures don't stop the lifecycle
+func (l *Lifecycle) event(w MarkedWorkload, eventType, reason, messageFmt string, args ...interface{}) {
+	l.eventOn(l.reference(w), eventType, reason, messageFmt, args...)
+}
+
+// reference returns reference to the workload for events, nil if events aren't recorded
+func (l *Lifecycle) reference(w MarkedWorkload) *v1.ObjectReference {
+	if l.recorder == nil {
+		return nil
+	}
+	ref, err := l.recorder.Reference(w.Namespace, w.Kind, w.Name)
+	if err != nil {
+		klog.V(4).Infof("Can't record events on %v/%v/%v: %v", w.Namespace, w.Kind, w.Name, err)
+		return nil
+	}
+
+	return ref
+}
+
+// eventOn records lifecycle event on the referenced workload
+func (l *Lifecycle) eventOn(ref *v1.ObjectReference, eventType, reason, messageFmt string, args ...interface{}) {
+	if ref == nil {
+		return
+	}
+	l.recorder.Eventf(ref, eventType, reason, messageFmt, args...)
+}
+
+// WithConfig returns the lifecycle with other delays
+func (l *Lifecycle) WithConfig(config LifecycleConfig) *Lifecycle {
+	c := *l
+	c.config = config
+
+	return &c
+}
@@ -0,0 +1,231 @@
+package ukubernetes
+
+import (
+	"encoding/json"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog"
+)
+
+// Annotations and label stamped on idle workloads
+const (
+	AnnotationIdleSince = "useless-operator/idle-since"
+	AnnotationLastScan  = "useless-operator/last-scan"
+	AnnotationReason    = "useless-operator/reason"
+	// When the current state was set
+	AnnotationStateSince = "useless-operator/state-since"
+	// Replicas before the workload was scaled down
+	AnnotationReplicas = "useless-operator/replicas"
+	LabelState         = "useless-operator/state"
+)
+
+// States of idle workloads
+const (
+	StateIdle       = "idle"
+	StateWarned     = "warned"
+	StateScaledDown = "scaled-down"
+)
+
+// Kinds of workloads which may be found idle
+var markedKinds = []string{
+	KindDeployment,
+	KindStatefulSet,
+	KindDaemonSet,
+	KindReplicaSet,
+	KindReplicationController,
+	KindCronJob,
+}
+
+// Marker stamps idle workloads with status annotations and label, so other tooling can select them
+type Marker struct {
+	dClient   dynamic.Interface
+	resources *servedResources
+}
+
+// Marks of a workload
+type Marks struct {
+	State      string
+	StateSince time.Time
+	IdleSince  time.Time
+	LastScan   time.Time
+	Reason     string
+}
+
+// MarkedWorkload is a workload with marks
+type MarkedWorkload struct {
+	Namespace string
+	Kind      string
+	Name      string
+	Marks     Marks
+}
+
+// NewMarker returns marker of workloads
+func NewMarker(kClient *kubernetes.Clientset, dClient dynamic.Interface) *Marker {
+	return &Marker{dClient: dClient, resources: newServedResources(kClient)}
+}
+
+// Get returns current marks of the workload, empty state means the workload isn't marked
+func (m *Marker) Get(namespace, kind, name string) (Marks, error) {
+	gvr, err := m.resources.get(kind)
+	if err != nil {
+		return Marks{}, err
+	}
+	obj, err := m.dClient.Resource(gvr).Namespace(namespace).Get(name, metav1.GetOptions{})
+	if err != nil {
+		return Marks{}, err
+	}
+
+	return marksOf(obj.GetLabels(), obj.GetAnnotations()), nil
+}
+
+// Mark stamps the workload found idle by the scan. Idle-since is kept from previous scans,
+// state is set to idle unless a later state is already set.
+func (m *Marker) Mark(namespace, kind, name, reason string, scan time.Time) (Marks, error) {
+	marks, err := m.Get(namespace, kind, name)
+	if err != nil {
+		return marks, err
+	}
+
+	if marks.State == "" {
+		marks.State = StateIdle
+		marks.StateSince = scan
+		marks.IdleSince = scan
+	}
+	if marks.IdleSince.IsZero() {
+		marks.IdleSince = scan
+	}
+	if marks.StateSince.IsZero() {
+		marks.StateSince = scan
+	}
+	marks.LastScan = scan
+	marks.Reason = reason
+
+	return marks, m.patch(namespace, kind, name, map[string]interface{}{
+		AnnotationIdleSince:  marks.IdleSince.UTC().Format(time.RFC3339),
+		AnnotationLastScan:   marks.LastScan.UTC().Format(time.RFC3339),
+		AnnotationReason:     marks.Reason,
+		AnnotationStateSince: marks.StateSince.UTC().Format(time.RFC3339),
+	}, map[string]interface{}{
+		LabelState: marks.State,
+	})
+}
+
+// SetState changes state label of the marked workload
+func (m *Marker) SetState(namespace, kind, name, state string, since time.Time) error {
+	return m.patch(namespace, kind, name, map[string]interface{}{
+		AnnotationStateSince: since.UTC().Format(time.RFC3339),
+	}, map[string]interface{}{
+		LabelState: state,
+	})
+}
+
+// Unmark removes annotations and label of the workload
+func (m *Marker) Unmark(namespace, kind, name string) error {
+	return m.patch(namespace, kind, name, map[string]interface{}{
+		AnnotationIdleSince:  nil,
+		AnnotationLastScan:   nil,
+		AnnotationReason:     nil,
+		AnnotationStateSince: nil,
+		AnnotationReplicas:   nil,
+	}, map[string]interface{}{
+		LabelState: nil,
+	})
+}
+
+// List returns workloads in the state, empty state means all marked workloads
+func (m *Marker) List(state string) ([]MarkedWorkload, error) {
+	selector := LabelState
+	if state != "" {
+		selector = LabelState + "=" + state
+	}
+
+	var marked []MarkedWorkload
+	for _, kind := range markedKinds {
+		gvr, err := m.resources.get(kind)
+		if err != nil {
+			klog.V(4).Infof("Can't look for marked workloads: %v", err)
+			continue
+		}
+		list, err := m.dClient.Resource(gvr).Namespace("").List(metav1.ListOptions{LabelSelector: selector})
+		if err != nil {
+			return nil, err
+		}
+		for _, obj := range list.Items {
+			marked = append(marked, MarkedWorkload{
+				Namespace: obj.GetNamespace(),
+				Kind:      kind,
+				Name:      obj.GetName(),
+				Marks:     marksOf(obj.GetLabels(), obj.GetAnnotations()),
+			})
+		}
+	}
+
+	return marked, nil
+}
+
+// UnmarkResumed removes marks of idle and warned workloads which aren't in idleWorkloads ("namespace/Kind/name")
+// anymore, because their traffic resumed. Scaled down workloads have no traffic and keep their marks until
+// they're restored. Returns unmarked workloads ("namespace/Kind/name").
+func (m *Marker) UnmarkResumed(idleWorkloads map[string]bool) ([]string, error) {
+	marked, err := m.List("")
+	if err != nil {
+		return nil, err
+	}
+
+	var unmarked []string
+	for _, w := range marked {
+		key := w.Namespace + "/" + w.Kind + "/" + w.Name
+		if idleWorkloads[key] || w.Marks.State == StateScaledDown {
+			continue
+		}
+		if err := m.Unmark(w.Namespace, w.Kind, w.Name); err != nil {
+			klog.V(4).Infof("%v (resource may disappear)", err)
+			continue
+		}
+		unmarked = append(unmarked, key)
+	}
+
+	return unmarked, nil
+}
+
+// patch merges annotations and labels into the workload's metadata, nil values remove keys
+func (m *Marker) patch(namespace, kind, name string, annotations, labels map[string]interface{}) error {
+	gvr, err := m.resources.get(kind)
+	if err != nil {
+		return err
+	}
+
+	metadata := map[string]interface{}{}
+	if annotations != nil {
+		metadata["annotations"] = annotations
+	}
+	if labels != nil {
+		metadata["labels"] = labels
+	}
+	data, err := json.Marshal(map[string]interface{}{"metadata": metadata})
+	if err != nil {
+		return err
+	}
+
+	_, err = m.dClient.Resource(gvr).Namespace(namespace).Patch(name, types.MergePatchType, data,
+		metav1.PatchOptions{})
+
+	return err
+}
+
+// marksOf parses marks from workload's labels and annotations
+func marksOf(labels, annotations map[string]string) Marks {
+	marks := Marks{
+		State:  labels[LabelState],
+		Reason: annotations[AnnotationReason],
+	}
+	marks.StateSince, _ = time.Parse(time.RFC3339, annotations[AnnotationStateSince])
+	marks.IdleSince, _ = time.Parse(time.RFC3339, annotations[AnnotationIdleSince])
+	marks.LastScan, _ = time.Parse(time.RFC3339, annotations[AnnotationLastScan])
+
+	return marks
+}
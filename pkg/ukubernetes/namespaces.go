@@ -0,0 +1,193 @@
+package ukubernetes
+
+import (
+	"sort"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Reasons why a namespace is considered abandoned
+const (
+	ReasonAllWorkloadsIdle = "all workloads idle"
+	ReasonNoPods           = "no pods"
+	ReasonNotModified      = "no modifications"
+)
+
+// AbandonedNamespace is a namespace whose workloads are all idle, which has no pods,
+// or whose objects weren't modified for a long time
+type AbandonedNamespace struct {
+	Name          string
+	Reasons       []string
+	Workloads     int // Deployments, StatefulSets and DaemonSets
+	IdleWorkloads int
+	Pods          int
+	CronJobs      int   // not suspended, they run pods only on schedule
+	CPU           int64 // milli, requests of all pods
+	Memory        int64 // bytes, requests of all pods
+	LastModified  time.Time
+	// Namespace's annotations and labels with owner keys
+	Owners map[string]string
+}
+
+// GetAbandonedNamespaces returns namespaces whose workloads are all in idleWorkloads ("namespace/Kind/name"),
+// namespaces without pods and scheduled CronJobs and namespaces without modifications for notModifiedAge (0 disables).
+// Values of namespace's annotations and labels with ownerKeys are returned as owners.
+func GetAbandonedNamespaces(kClient *kubernetes.Clientset, dClient dynamic.Interface, idleWorkloads map[string]bool,
+	notModifiedAge time.Duration, ownerKeys []string) ([]AbandonedNamespace, error) {
+
+	namespaces, err := kClient.CoreV1().Namespaces().List(metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	verdicts := map[string]*AbandonedNamespace{}
+	for _, ns := range namespaces.Items {
+		if systemNamespaces[ns.Name] || ns.Name == metav1.NamespaceDefault {
+			continue
+		}
+		verdict := &AbandonedNamespace{Name: ns.Name, Owners: map[string]string{}}
+		verdict.touch(&ns.ObjectMeta)
+		for _, key := range ownerKeys {
+			if value, ok := ns.Annotations[key]; ok {
+				verdict.Owners[key] = value
+			} else if value, ok := ns.Labels[key]; ok {
+				verdict.Owners[key] = value
+			}
+		}
+		verdicts[ns.Name] = verdict
+	}
+
+	workload := func(meta *metav1.ObjectMeta, kind string) {
+		if verdict, ok := verdicts[meta.Namespace]; ok {
+			verdict.Workloads++
+			if idleWorkloads[meta.Namespace+"/"+kind+"/"+meta.Name] {
+				verdict.IdleWorkloads++
+			}
+		}
+	}
+
+	pods, err := kClient.CoreV1().Pods("").List(metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		verdict, ok := verdicts[pod.Namespace]
+		if !ok {
+			continue
+		}
+		verdict.touch(&pod.ObjectMeta)
+		if pod.Status.Phase == v1.PodSucceeded || pod.Status.Phase == v1.PodFailed {
+			continue
+		}
+		verdict.Pods++
+		cpu, mem := PodRequests(pod)
+		verdict.CPU += cpu
+		verdict.Memory += mem
+	}
+
+	deployments, err := kClient.AppsV1().Deployments("").List(metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for i := range deployments.Items {
+		workload(&deployments.Items[i].ObjectMeta, KindDeployment)
+		touch(verdicts, &deployments.Items[i].ObjectMeta)
+	}
+
+	statefulSets, err := kClient.AppsV1().StatefulSets("").List(metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for i := range statefulSets.Items {
+		workload(&statefulSets.Items[i].ObjectMeta, KindStatefulSet)
+		touch(verdicts, &statefulSets.Items[i].ObjectMeta)
+	}
+
+	daemonSets, err := kClient.AppsV1().DaemonSets("").List(metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for i := range daemonSets.Items {
+		workload(&daemonSets.Items[i].ObjectMeta, KindDaemonSet)
+		touch(verdicts, &daemonSets.Items[i].ObjectMeta)
+	}
+
+	cronJobs, err := ListCronJobs(kClient, dClient, "")
+	if err != nil {
+		return nil, err
+	}
+	for i := range cronJobs {
+		touch(verdicts, &cronJobs[i].ObjectMeta)
+		if cronJobs[i].Spec.Suspend != nil && *cronJobs[i].Spec.Suspend {
+			continue
+		}
+		if verdict, ok := verdicts[cronJobs[i].Namespace]; ok {
+			verdict.CronJobs++
+		}
+	}
+
+	services, err := kClient.CoreV1().Services("").List(metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for i := range services.Items {
+		touch(verdicts, &services.Items[i].ObjectMeta)
+	}
+
+	configMaps, err := kClient.CoreV1().ConfigMaps("").List(metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for i := range configMaps.Items {
+		// Kubernetes updates root CA ConfigMaps in every namespace
+		if systemConfigMaps[configMaps.Items[i].Name] {
+			continue
+		}
+		touch(verdicts, &configMaps.Items[i].ObjectMeta)
+	}
+
+	var abandoned []AbandonedNamespace
+	for _, verdict := range verdicts {
+		if verdict.Pods == 0 && verdict.CronJobs == 0 {
+			verdict.Reasons = append(verdict.Reasons, ReasonNoPods)
+		} else if verdict.Workloads > 0 && verdict.IdleWorkloads == verdict.Workloads {
+			verdict.Reasons = append(verdict.Reasons, ReasonAllWorkloadsIdle)
+		}
+		if notModifiedAge > 0 && time.Since(verdict.LastModified) >= notModifiedAge {
+			verdict.Reasons = append(verdict.Reasons, ReasonNotModified)
+		}
+		if len(verdict.Reasons) > 0 {
+			abandoned = append(abandoned, *verdict)
+		}
+	}
+
+	sort.Slice(abandoned, func(i, j int) bool {
+		return abandoned[i].Name < abandoned[j].Name
+	})
+
+	return abandoned, nil
+}
+
+// touch updates last modification time of object's namespace
+func touch(verdicts map[string]*AbandonedNamespace, meta *metav1.ObjectMeta) {
+	if verdict, ok := verdicts[meta.Namespace]; ok {
+		verdict.touch(meta)
+	}
+}
+
+// touch updates last modification time by object's creation and managed fields timestamps
+func (verdict *AbandonedNamespace) touch(meta *metav1.ObjectMeta) {
+	if meta.CreationTimestamp.After(verdict.LastModified) {
+		verdict.LastModified = meta.CreationTimestamp.Time
+	}
+	for _, field := range meta.ManagedFields {
+		if field.Time != nil && field.Time.After(verdict.LastModified) {
+			verdict.LastModified = field.Time.Time
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package ukubernetes
+
+import (
+	"strings"
+
+	"k8s.io/klog"
+)
+
+// Values of keys like app.kubernetes.io/managed-by naming deployment tools rather than teams
+var toolOwners = map[string]bool{
+	"helm":      true,
+	"tiller":    true,
+	"kustomize": true,
+	"argocd":    true,
+	"flux":      true,
+}
+
+// OwnerResolver finds owners of objects by annotation and label keys (in order of preference),
+// falling back to the same keys of their namespaces
+type OwnerResolver struct {
+	metadata *MetadataCache
+	keys     []string
+}
+
+// NewOwnerResolver returns resolver looking up given annotation and label keys
+func NewOwnerResolver(metadata *MetadataCache, keys []string) *OwnerResolver {
+	return &OwnerResolver{metadata: metadata, keys: keys}
+}
+
+// Resolve returns owner of the object or empty string if neither the object nor its namespace has owner keys.
+// Empty namespace means cluster-scoped object.
+func (r *OwnerResolver) Resolve(namespace, kind, name string) string {
+	owner := r.resolve(namespace, kind, name)
+	if owner == "" && namespace != "" {
+		owner = r.resolve("", "Namespace", namespace)
+	}
+
+	return owner
+}
+
+// resolve returns owner of the object itself, deployment tools are skipped so namespace's owner is used
+func (r *OwnerResolver) resolve(namespace, kind, name string) string {
+	meta, err := r.metadata.Get(namespace, kind, name)
+	if err != nil {
+		klog.V(4).Infof("Can't resolve owner: %v (resource may disappear)", err)
+		return ""
+	}
+
+	for _, key := range r.keys {
+		for _, value := range []string{meta.Annotations[key], meta.Labels[key]} {
+			if value != "" && !toolOwners[strings.ToLower(value)] {
+				return value
+			}
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,20 @@
+package ukubernetes
+
+import "testing"
+
+func TestResolveOwner(t *testing.T) {
+	metadata := &MetadataCache{objects: map[string]*Metadata{
+		"/Namespace/shop":        {Labels: map[string]string{"team": "payments"}},
+		"shop/Deployment/cart":   {Labels: map[string]string{"app.kubernetes.io/managed-by": "Helm"}},
+		"shop/Deployment/search": {Annotations: map[string]string{"owner": "search"}},
+	}, errors: map[string]error{}}
+	r := NewOwnerResolver(metadata, []string{"owner", "team", "app.kubernetes.io/managed-by"})
+
+	// Helm isn't a team, the namespace's owner is used
+	if owner := r.Resolve("shop", "Deployment", "cart"); owner != "payments" {
+		t.Errorf("expected owner of the namespace, got %q", owner)
+	}
+	if owner := r.Resolve("shop", "Deployment", "search"); owner != "search" {
+		t.Errorf("expected owner of the object, got %q", owner)
+	}
+}
@@ -0,0 +1,249 @@
+package ukubernetes
+
+import (
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Kinds of configuration objects
+const (
+	KindConfigMap = "ConfigMap"
+	KindSecret    = "Secret"
+)
+
+// ConfigMaps created by Kubernetes itself in every namespace
+var systemConfigMaps = map[string]bool{
+	"kube-root-ca.crt": true,
+}
+
+// Secret types managed by Kubernetes or tools which don't reference them from pods
+var systemSecretTypes = map[v1.SecretType]bool{
+	v1.SecretTypeServiceAccountToken: true,
+	v1.SecretTypeBootstrapToken:      true,
+	"helm.sh/release.v1":             true,
+}
+
+// Namespaces of cluster components, their configuration is read via API rather than mounted
+var systemNamespaces = map[string]bool{
+	"kube-system":     true,
+	"kube-public":     true,
+	"kube-node-lease": true,
+}
+
+// Label of objects the operator creates itself (history, published reports), they are read via API
+const (
+	labelManagedBy = "app.kubernetes.io/managed-by"
+	managedBy      = "useless-operator"
+)
+
+// UnreferencedObject is a ConfigMap or Secret not referenced by anything
+type UnreferencedObject struct {
+	Kind      string
+	Namespace string
+	Name      string
+	Type      string // Secret type
+	Age       time.Duration
+	Size      int64 // bytes of data
+}
+
+// GetUnreferencedConfigs returns ConfigMaps and Secrets not referenced by pods, workload templates,
+// ServiceAccounts or Ingress TLS sections, except objects of the operator. Empty namespace means all namespaces.
+func GetUnreferencedConfigs(kClient *kubernetes.Clientset, dClient dynamic.Interface, ingAPI *IngressAPI,
+	namespace string) ([]UnreferencedObject, error) {
+
+	refs, err := getConfigReferences(kClient, dClient, ingAPI, namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	var unreferenced []UnreferencedObject
+
+	configMaps, err := kClient.CoreV1().ConfigMaps(namespace).List(metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for _, cm := range configMaps.Items {
+		if systemNamespaces[cm.Namespace] || systemConfigMaps[cm.Name] || cm.Labels[labelManagedBy] == managedBy ||
+			refs[configRef(KindConfigMap, cm.Namespace, cm.Name)] {
+			continue
+		}
+		var size int64
+		for _, data := range cm.Data {
+			size += int64(len(data))
+		}
+		for _, data := range cm.BinaryData {
+			size += int64(len(data))
+		}
+		unreferenced = append(unreferenced, UnreferencedObject{
+			Kind:      KindConfigMap,
+			Namespace: cm.Namespace,
+			Name:      cm.Name,
+			Age:       time.Since(cm.CreationTimestamp.Time),
+			Size:      size,
+		})
+	}
+
+	secrets, err := kClient.CoreV1().Secrets(namespace).List(metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for _, secret := range secrets.Items {
+		if systemNamespaces[secret.Namespace] || systemSecretTypes[secret.Type] ||
+			secret.Labels[labelManagedBy] == managedBy || refs[configRef(KindSecret, secret.Namespace, secret.Name)] {
+			continue
+		}
+		var size int64
+		for _, data := range secret.Data {
+			size += int64(len(data))
+		}
+		unreferenced = append(unreferenced, UnreferencedObject{
+			Kind:      KindSecret,
+			Namespace: secret.Namespace,
+			Name:      secret.Name,
+			Type:      string(secret.Type),
+			Age:       time.Since(secret.CreationTimestamp.Time),
+			Size:      size,
+		})
+	}
+
+	return unreferenced, nil
+}
+
+// getConfigReferences returns set of referenced ConfigMaps and Secrets (see configRef)
+func getConfigReferences(kClient *kubernetes.Clientset, dClient dynamic.Interface, ingAPI *IngressAPI,
+	namespace string) (map[string]bool, error) {
+
+	refs := map[string]bool{}
+
+	pods, err := kClient.CoreV1().Pods(namespace).List(metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for i := range pods.Items {
+		addPodSpecRefs(refs, pods.Items[i].Namespace, &pods.Items[i].Spec)
+	}
+
+	// Workloads scaled to zero have no pods but still need their configuration
+	deployments, err := kClient.AppsV1().Deployments(namespace).List(metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for i := range deployments.Items {
+		addPodSpecRefs(refs, deployments.Items[i].Namespace, &deployments.Items[i].Spec.Template.Spec)
+	}
+
+	statefulSets, err := kClient.AppsV1().StatefulSets(namespace).List(metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for i := range statefulSets.Items {
+		addPodSpecRefs(refs, statefulSets.Items[i].Namespace, &statefulSets.Items[i].Spec.Template.Spec)
+	}
+
+	daemonSets, err := kClient.AppsV1().DaemonSets(namespace).List(metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for i := range daemonSets.Items {
+		addPodSpecRefs(refs, daemonSets.Items[i].Namespace, &daemonSets.Items[i].Spec.Template.Spec)
+	}
+
+	jobs, err := kClient.BatchV1().Jobs(namespace).List(metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for i := range jobs.Items {
+		addPodSpecRefs(refs, jobs.Items[i].Namespace, &jobs.Items[i].Spec.Template.Spec)
+	}
+
+	cronJobs, err := ListCronJobs(kClient, dClient, namespace)
+	if err != nil {
+		return nil, err
+	}
+	for i := range cronJobs {
+		addPodSpecRefs(refs, cronJobs[i].Namespace, &cronJobs[i].Spec.JobTemplate.Spec.Template.Spec)
+	}
+
+	serviceAccounts, err := kClient.CoreV1().ServiceAccounts(namespace).List(metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for _, sa := range serviceAccounts.Items {
+		for _, secret := range sa.Secrets {
+			refs[configRef(KindSecret, sa.Namespace, secret.Name)] = true
+		}
+		for _, secret := range sa.ImagePullSecrets {
+			refs[configRef(KindSecret, sa.Namespace, secret.Name)] = true
+		}
+	}
+
+	ingresses, err := ingAPI.ListAll(namespace)
+	if err != nil {
+		return nil, err
+	}
+	for _, ing := range ingresses {
+		for _, secret := range ing.TLSSecrets {
+			refs[configRef(KindSecret, ing.Namespace, secret)] = true
+		}
+	}
+
+	return refs, nil
+}
+
+// addPodSpecRefs adds ConfigMaps and Secrets referenced by volumes, environment and image pull secrets
+func addPodSpecRefs(refs map[string]bool, namespace string, spec *v1.PodSpec) {
+	for _, secret := range spec.ImagePullSecrets {
+		refs[configRef(KindSecret, namespace, secret.Name)] = true
+	}
+
+	for _, volume := range spec.Volumes {
+		if volume.ConfigMap != nil {
+			refs[configRef(KindConfigMap, namespace, volume.ConfigMap.Name)] = true
+		}
+		if volume.Secret != nil {
+			refs[configRef(KindSecret, namespace, volume.Secret.SecretName)] = true
+		}
+		if volume.Projected != nil {
+			for _, source := range volume.Projected.Sources {
+				if source.ConfigMap != nil {
+					refs[configRef(KindConfigMap, namespace, source.ConfigMap.Name)] = true
+				}
+				if source.Secret != nil {
+					refs[configRef(KindSecret, namespace, source.Secret.Name)] = true
+				}
+			}
+		}
+	}
+
+	containers := append(append([]v1.Container{}, spec.InitContainers...), spec.Containers...)
+	for _, container := range containers {
+		for _, envFrom := range container.EnvFrom {
+			if envFrom.ConfigMapRef != nil {
+				refs[configRef(KindConfigMap, namespace, envFrom.ConfigMapRef.Name)] = true
+			}
+			if envFrom.SecretRef != nil {
+				refs[configRef(KindSecret, namespace, envFrom.SecretRef.Name)] = true
+			}
+		}
+		for _, env := range container.Env {
+			if env.ValueFrom == nil {
+				continue
+			}
+			if env.ValueFrom.ConfigMapKeyRef != nil {
+				refs[configRef(KindConfigMap, namespace, env.ValueFrom.ConfigMapKeyRef.Name)] = true
+			}
+			if env.ValueFrom.SecretKeyRef != nil {
+				refs[configRef(KindSecret, namespace, env.ValueFrom.SecretKeyRef.Name)] = true
+			}
+		}
+	}
+}
+
+// configRef returns key of referenced object
+func configRef(kind, namespace, name string) string {
+	return kind + "/" + namespace + "/" + name
+}
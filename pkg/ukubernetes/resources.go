@@ -0,0 +1,127 @@
+package ukubernetes
+
+import (
+	"fmt"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+)
+
+// API versions of kinds findings are reported for, in order of preference
+var kindGroupVersions = map[string][]schema.GroupVersion{
+	"Namespace":               {{Version: "v1"}},
+	"Pod":                     {{Version: "v1"}},
+	"Service":                 {{Version: "v1"}},
+	KindConfigMap:             {{Version: "v1"}},
+	KindSecret:                {{Version: "v1"}},
+	KindPersistentVolumeClaim: {{Version: "v1"}},
+	KindPersistentVolume:      {{Version: "v1"}},
+	KindReplicationController: {{Version: "v1"}},
+	KindDeployment:            {{Group: "apps", Version: "v1"}},
+	KindStatefulSet:           {{Group: "apps", Version: "v1"}},
+	KindDaemonSet:             {{Group: "apps", Version: "v1"}},
+	KindReplicaSet:            {{Group: "apps", Version: "v1"}},
+	KindJob:                   {{Group: "batch", Version: "v1"}},
+	KindCronJob:               cronJobGroupVersions,
+	"HorizontalPodAutoscaler": {{Group: "autoscaling", Version: "v2"}, {Group: "autoscaling", Version: "v1"}},
+	"Ingress":                 ingressGroupVersions,
+	KindHTTPRoute:             gatewayGroupVersions,
+	KindGRPCRoute:             gatewayGroupVersions,
+}
+
+// servedResources resolves kinds to resources served by the API server
+type servedResources struct {
+	kClient *kubernetes.Clientset
+	gvrs    map[string]schema.GroupVersionResource
+}
+
+// newServedResources returns resolver caching resolved kinds
+func newServedResources(kClient *kubernetes.Clientset) *servedResources {
+	return &servedResources{kClient: kClient, gvrs: map[string]schema.GroupVersionResource{}}
+}
+
+// get returns served resource of the kind
+func (r *servedResources) get(kind string) (schema.GroupVersionResource, error) {
+	if gvr, ok := r.gvrs[kind]; ok {
+		return gvr, nil
+	}
+
+	groupVersions, ok := kindGroupVersions[kind]
+	if !ok {
+		return schema.GroupVersionResource{}, fmt.Errorf("unknown kind %v", kind)
+	}
+	gvr, found, err := GetServedResource(r.kClient, groupVersions, resourceOf(kind))
+	if err != nil {
+		return gvr, err
+	}
+	if !found {
+		return gvr, fmt.Errorf("resource of %v not served", kind)
+	}
+	r.gvrs[kind] = gvr
+
+	return gvr, nil
+}
+
+// Metadata is labels and annotations of an object
+type Metadata struct {
+	Labels      map[string]string
+	Annotations map[string]string
+}
+
+// MetadataCache fetches labels and annotations of objects once
+type MetadataCache struct {
+	dClient   dynamic.Interface
+	resources *servedResources
+	// By "namespace/Kind/name"
+	objects map[string]*Metadata
+	errors  map[string]error
+}
+
+// NewMetadataCache returns empty cache
+func NewMetadataCache(kClient *kubernetes.Clientset, dClient dynamic.Interface) *MetadataCache {
+	return &MetadataCache{
+		dClient:   dClient,
+		resources: newServedResources(kClient),
+		objects:   map[string]*Metadata{},
+		errors:    map[string]error{},
+	}
+}
+
+// Get returns metadata of the object, empty namespace means cluster-scoped object
+func (c *MetadataCache) Get(namespace, kind, name string) (*Metadata, error) {
+	key := namespace + "/" + kind + "/" + name
+	if meta, ok := c.objects[key]; ok {
+		return meta, nil
+	}
+	if err, ok := c.errors[key]; ok {
+		return nil, err
+	}
+
+	gvr, err := c.resources.get(kind)
+	if err != nil {
+		c.errors[key] = err
+		return nil, err
+	}
+	obj, err := c.dClient.Resource(gvr).Namespace(namespace).Get(name, metav1.GetOptions{})
+	if err != nil {
+		c.errors[key] = err
+		return nil, err
+	}
+	meta := &Metadata{Labels: obj.GetLabels(), Annotations: obj.GetAnnotations()}
+	c.objects[key] = meta
+
+	return meta, nil
+}
+
+// resourceOf returns resource name of the kind (e.g. "ingresses" of "Ingress")
+func resourceOf(kind string) string {
+	resource := strings.ToLower(kind)
+	if strings.HasSuffix(resource, "s") {
+		return resource + "es"
+	}
+
+	return resource + "s"
+}
@@ -0,0 +1,142 @@
+package ukubernetes
+
+import (
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Kinds of storage objects
+const (
+	KindPersistentVolumeClaim = "PersistentVolumeClaim"
+	KindPersistentVolume      = "PersistentVolume"
+)
+
+// Reasons why a volume is considered unused
+const (
+	ReasonNotMounted         = "not mounted by any pod"
+	ReasonMountedByIdlePods  = "mounted only by idle pods"
+	ReasonUsedBytesUnchanged = "used bytes unchanged during observed period"
+	ReasonVolumeReleased     = "volume released by its claim"
+	ReasonVolumeAvailable    = "volume not bound to any claim"
+)
+
+// UnusedVolume is a PersistentVolumeClaim or PersistentVolume which keeps storage for nothing
+type UnusedVolume struct {
+	Kind         string
+	Namespace    string // empty for PersistentVolumes
+	Name         string
+	Capacity     int64 // bytes
+	StorageClass string
+	Reason       string
+	Pods         []string // pods mounting the claim
+}
+
+// GetUnusedVolumes returns claims not mounted by any running pod, claims mounted only by idlePods,
+// claims from unchangedClaims and Released/Available PersistentVolumes. idlePods and unchangedClaims are keyed
+// by "namespace/name".
+func GetUnusedVolumes(kClient *kubernetes.Clientset, idlePods map[string]bool,
+	unchangedClaims map[string]bool) ([]UnusedVolume, error) {
+
+	pods, err := kClient.CoreV1().Pods("").List(metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	// Pods mounting each claim ("namespace/claim")
+	claimPods := map[string][]string{}
+	for _, pod := range pods.Items {
+		// Finished pods don't hold volumes
+		if pod.Status.Phase == v1.PodSucceeded || pod.Status.Phase == v1.PodFailed {
+			continue
+		}
+		for _, volume := range pod.Spec.Volumes {
+			if volume.PersistentVolumeClaim == nil {
+				continue
+			}
+			key := pod.Namespace + "/" + volume.PersistentVolumeClaim.ClaimName
+			claimPods[key] = append(claimPods[key], pod.Name)
+		}
+	}
+
+	claims, err := kClient.CoreV1().PersistentVolumeClaims("").List(metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	var unused []UnusedVolume
+	for _, claim := range claims.Items {
+		key := claim.Namespace + "/" + claim.Name
+		mountedBy := claimPods[key]
+
+		reason := ""
+		switch {
+		case len(mountedBy) == 0:
+			reason = ReasonNotMounted
+		case allIdle(claim.Namespace, mountedBy, idlePods):
+			reason = ReasonMountedByIdlePods
+		case unchangedClaims[key]:
+			reason = ReasonUsedBytesUnchanged
+		default:
+			continue
+		}
+
+		capacity := claim.Status.Capacity[v1.ResourceStorage]
+		if claim.Status.Phase != v1.ClaimBound {
+			capacity = claim.Spec.Resources.Requests[v1.ResourceStorage]
+		}
+		storageClass := ""
+		if claim.Spec.StorageClassName != nil {
+			storageClass = *claim.Spec.StorageClassName
+		}
+
+		unused = append(unused, UnusedVolume{
+			Kind:         KindPersistentVolumeClaim,
+			Namespace:    claim.Namespace,
+			Name:         claim.Name,
+			Capacity:     capacity.Value(),
+			StorageClass: storageClass,
+			Reason:       reason,
+			Pods:         mountedBy,
+		})
+	}
+
+	volumes, err := kClient.CoreV1().PersistentVolumes().List(metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, volume := range volumes.Items {
+		reason := ""
+		switch volume.Status.Phase {
+		case v1.VolumeReleased:
+			reason = ReasonVolumeReleased
+		case v1.VolumeAvailable:
+			reason = ReasonVolumeAvailable
+		default:
+			continue
+		}
+
+		capacity := volume.Spec.Capacity[v1.ResourceStorage]
+		unused = append(unused, UnusedVolume{
+			Kind:         KindPersistentVolume,
+			Name:         volume.Name,
+			Capacity:     capacity.Value(),
+			StorageClass: volume.Spec.StorageClassName,
+			Reason:       reason,
+		})
+	}
+
+	return unused, nil
+}
+
+// allIdle checks whether all pods are in idlePods
+func allIdle(namespace string, pods []string, idlePods map[string]bool) bool {
+	for _, pod := range pods {
+		if !idlePods[namespace+"/"+pod] {
+			return false
+		}
+	}
+
+	return true
+}
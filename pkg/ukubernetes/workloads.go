@@ -0,0 +1,199 @@
+package ukubernetes
+
+import (
+	"fmt"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1beta1 "k8s.io/api/batch/v1beta1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Workload kinds owning pods
+const (
+	KindDeployment            = "Deployment"
+	KindStatefulSet           = "StatefulSet"
+	KindDaemonSet             = "DaemonSet"
+	KindReplicaSet            = "ReplicaSet"
+	KindReplicationController = "ReplicationController"
+	KindJob                   = "Job"
+	KindCronJob               = "CronJob"
+)
+
+// Workload is the top-level controller of a pod
+type Workload struct {
+	Kind string
+	Name string
+}
+
+// GetPodWorkload returns pod's top-level controller, following ReplicaSet to Deployment and Job to CronJob.
+// Empty Workload is returned for bare pods.
+func GetPodWorkload(kClient *kubernetes.Clientset, pod *v1.Pod) (Workload, error) {
+	ref := metav1.GetControllerOf(pod)
+	if ref == nil {
+		return Workload{}, nil
+	}
+
+	switch ref.Kind {
+	case KindReplicaSet:
+		replicaSet, err := kClient.AppsV1().ReplicaSets(pod.Namespace).Get(ref.Name, metav1.GetOptions{})
+		if err != nil {
+			return Workload{}, err
+		}
+		if owner := metav1.GetControllerOf(replicaSet); owner != nil && owner.Kind == KindDeployment {
+			return Workload{Kind: KindDeployment, Name: owner.Name}, nil
+		}
+	case KindJob:
+		job, err := kClient.BatchV1().Jobs(pod.Namespace).Get(ref.Name, metav1.GetOptions{})
+		if err != nil {
+			return Workload{}, err
+		}
+		if owner := metav1.GetControllerOf(job); owner != nil && owner.Kind == KindCronJob {
+			return Workload{Kind: KindCronJob, Name: owner.Name}, nil
+		}
+	}
+
+	return Workload{Kind: ref.Kind, Name: ref.Name}, nil
+}
+
+// GetServicePods returns pods selected by service. Services without selector have no pods.
+func GetServicePods(kClient *kubernetes.Clientset, namespace, serviceName string) ([]v1.Pod, error) {
+	selector, err := GetSvcSelectorByIngressBackend(kClient, namespace, serviceName)
+	if err != nil {
+		return nil, err
+	}
+
+	// Empty selector matches every pod in the namespace
+	if len(selector) == 0 {
+		return nil, nil
+	}
+
+	pods, err := GetPodsBySelector(kClient, namespace, selector)
+	if err != nil {
+		return nil, err
+	}
+
+	return pods.Items, nil
+}
+
+// GetWorkloadPods returns pods of the workload. Empty kind means the first found of Deployment,
+// StatefulSet and DaemonSet with given name.
+func GetWorkloadPods(kClient *kubernetes.Clientset, namespace, kind, name string) (Workload, []v1.Pod, error) {
+	kinds := []string{kind}
+	if kind == "" {
+		kinds = []string{KindDeployment, KindStatefulSet, KindDaemonSet}
+	}
+
+	for _, k := range kinds {
+		var selector *metav1.LabelSelector
+		var err error
+		switch k {
+		case KindDeployment:
+			var obj *appsv1.Deployment
+			obj, err = kClient.AppsV1().Deployments(namespace).Get(name, metav1.GetOptions{})
+			if err == nil {
+				selector = obj.Spec.Selector
+			}
+		case KindStatefulSet:
+			var obj *appsv1.StatefulSet
+			obj, err = kClient.AppsV1().StatefulSets(namespace).Get(name, metav1.GetOptions{})
+			if err == nil {
+				selector = obj.Spec.Selector
+			}
+		case KindDaemonSet:
+			var obj *appsv1.DaemonSet
+			obj, err = kClient.AppsV1().DaemonSets(namespace).Get(name, metav1.GetOptions{})
+			if err == nil {
+				selector = obj.Spec.Selector
+			}
+		default:
+			return Workload{}, nil, fmt.Errorf("unsupported workload kind: %v", k)
+		}
+		if errors.IsNotFound(err) && kind == "" {
+			continue
+		}
+		if err != nil {
+			return Workload{}, nil, err
+		}
+
+		labelSelector, err := metav1.LabelSelectorAsSelector(selector)
+		if err != nil {
+			return Workload{}, nil, err
+		}
+		pods, err := kClient.CoreV1().Pods(namespace).List(metav1.ListOptions{LabelSelector: labelSelector.String()})
+		if err != nil {
+			return Workload{}, nil, err
+		}
+
+		return Workload{Kind: k, Name: name}, pods.Items, nil
+	}
+
+	return Workload{}, nil, fmt.Errorf("workload %v/%v not found", namespace, name)
+}
+
+// CronJob API versions in order of preference
+var cronJobGroupVersions = []schema.GroupVersion{
+	{Group: "batch", Version: "v1"},
+	{Group: "batch", Version: "v1beta1"},
+}
+
+// CronJob of any served API version
+type CronJob struct {
+	batchv1beta1.CronJob
+	// Whether the API version reports status.lastSuccessfulTime (batch/v1 only)
+	SuccessReported bool
+	// Nil if the CronJob never succeeded
+	LastSuccessfulTime *metav1.Time
+}
+
+// ListCronJobs returns CronJobs using the API version served by the cluster (batch/v1beta1 is removed from
+// current clusters, batch/v1 is absent in old ones). Both versions are converted into batch/v1beta1 types,
+// their schemas are compatible except lastSuccessfulTime of batch/v1. Empty namespace means all namespaces.
+func ListCronJobs(kClient *kubernetes.Clientset, dClient dynamic.Interface, namespace string) ([]CronJob, error) {
+	gvr, found, err := GetServedResource(kClient, cronJobGroupVersions, "cronjobs")
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, nil
+	}
+
+	list, err := dClient.Resource(gvr).Namespace(namespace).List(metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	cronJobs := make([]CronJob, len(list.Items))
+	for i := range list.Items {
+		obj := list.Items[i].Object
+		err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj, &cronJobs[i].CronJob)
+		if err != nil {
+			return nil, err
+		}
+
+		if gvr.Version != "v1" {
+			continue
+		}
+		cronJobs[i].SuccessReported = true
+		value, found, err := unstructured.NestedString(obj, "status", "lastSuccessfulTime")
+		if err != nil || !found {
+			continue
+		}
+		at, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return nil, fmt.Errorf("CronJob %v/%v: bad lastSuccessfulTime: %v", cronJobs[i].Namespace,
+				cronJobs[i].Name, err)
+		}
+		lastSuccess := metav1.NewTime(at)
+		cronJobs[i].LastSuccessfulTime = &lastSuccess
+	}
+
+	return cronJobs, nil
+}
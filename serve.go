@@ -0,0 +1,183 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/Nastradamus/useless-operator/pkg/server"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+	"k8s.io/klog"
+)
+
+// Leader election timings, client-go defaults used by controllers
+const (
+	leaseDuration = 15 * time.Second
+	renewDeadline = 10 * time.Second
+	retryPeriod   = 2 * time.Second
+)
+
+// serveCommand scans every -interval minutes until SIGTERM or SIGINT, watching policies and serving health and
+// metrics meanwhile. With -leader-elect only the leader scans, the lease is released once its current scan finishes.
+func serveCommand(args []string) int {
+	o := &options{}
+	fs := newFlagSet("serve", o, commonFlags, detectionFlags, deliveryFlags, actionFlags, serveFlags)
+	if code, ok := parse(fs, args); !ok {
+		return code
+	}
+	if fs.NArg() > 0 {
+		fs.Usage()
+		return exitUsage
+	}
+	if err := o.validate(); err != nil {
+		_, _ = fmt.Fprintln(fs.Output(), err)
+		return exitUsage
+	}
+	if o.interval == 0 {
+		_, _ = fmt.Fprintln(fs.Output(), "-interval must be positive")
+		return exitUsage
+	}
+	startProfiling(o)
+
+	klog.V(0).Infof("Starting useless-operator %v...", version)
+	clusters, err := connectAll(o)
+	if err != nil {
+		klog.Error(err)
+		return exitError
+	}
+	stopPolicies := make(chan struct{})
+	defer close(stopPolicies)
+	for _, c := range clusters {
+		go c.policies.Run(stopPolicies)
+	}
+
+	status := server.NewStatus()
+	// Fails when the leader can't renew its lease, so a stuck leader gets restarted
+	watchdog := leaderelection.NewLeaderHealthzAdaptor(renewDeadline)
+	go func() {
+		klog.Exit(http.ListenAndServe(o.listenAddr, status.Handler(watchdog.Check)))
+	}()
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGTERM, syscall.SIGINT)
+
+	// Stops scans, the current scan finishes first
+	scans, stopScans := context.WithCancel(context.Background())
+	defer stopScans()
+	if !o.leaderElect {
+		go func() {
+			klog.V(0).Infof("Received %v, stopping after the current scan", <-signals)
+			stopScans()
+		}()
+		status.SetLeader(true)
+		scanEvery(scans, o, clusters, status)
+		return exitOK
+	}
+
+	host, err := os.Hostname()
+	if err != nil {
+		klog.Error(err)
+		return exitError
+	}
+	namespace := o.leaderElectNamespace
+	if namespace == "" {
+		namespace = os.Getenv("POD_NAMESPACE")
+	}
+	if namespace == "" {
+		namespace = "default"
+	}
+	// The lease of multi-cluster scans is kept in the first cluster
+	kClient := clusters[0].kClient
+	lock, err := resourcelock.New(resourcelock.LeasesResourceLock, namespace, o.leaderElectName,
+		kClient.CoreV1(), kClient.CoordinationV1(), resourcelock.ResourceLockConfig{Identity: host})
+	if err != nil {
+		klog.Error(err)
+		return exitError
+	}
+
+	// Releases the lease, cancelled only after scans are done
+	election, stopElection := context.WithCancel(context.Background())
+	scansDone := make(chan struct{})
+	go func() {
+		klog.V(0).Infof("Received %v, handing over leadership after the current scan", <-signals)
+		stopScans()
+		if status.Leader() {
+			<-scansDone
+		}
+		stopElection()
+	}()
+
+	klog.V(0).Infof("Waiting for leadership of lease %v/%v as %v", namespace, o.leaderElectName, host)
+	leaderelection.RunOrDie(election, leaderelection.LeaderElectionConfig{
+		Lock:            lock,
+		LeaseDuration:   leaseDuration,
+		RenewDeadline:   renewDeadline,
+		RetryPeriod:     retryPeriod,
+		ReleaseOnCancel: true,
+		WatchDog:        watchdog,
+		Name:            o.leaderElectName,
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: func(leading context.Context) {
+				klog.V(0).Infof("Started leading as %v", host)
+				status.SetLeader(true)
+				go func() {
+					// Losing the lease stops scans too
+					select {
+					case <-leading.Done():
+						stopScans()
+					case <-scans.Done():
+					}
+				}()
+				scanEvery(scans, o, clusters, status)
+				close(scansDone)
+			},
+			OnStoppedLeading: func() {
+				status.SetLeader(false)
+				select {
+				case <-election.Done():
+					klog.V(0).Info("Leadership released")
+				default:
+					// Another replica may be acting already
+					klog.Exit("Leadership lost")
+				}
+			},
+			OnNewLeader: func(identity string) {
+				if identity != host {
+					klog.V(1).Infof("Current leader: %v", identity)
+				}
+			},
+		},
+	})
+
+	return exitOK
+}
+
+// scanEvery scans until ctx is cancelled, waiting -interval minutes between scans
+func scanEvery(ctx context.Context, o *options, clusters []*cluster, status *server.Status) {
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		default:
+		}
+
+		// Failed scans are retried by the next one
+		if scanReport, err := scan(o, clusters); err != nil {
+			klog.Errorf("Scan failed: %v", err)
+		} else {
+			status.SetReport(scanReport, time.Now())
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(time.Duration(o.interval) * time.Minute):
+		}
+	}
+}
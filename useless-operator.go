@@ -1,96 +1,223 @@
 package main
 
 import (
-	"bufio"
-	"flag"
 	"fmt"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/klog"
-	"net/http"
-	_ "net/http/pprof"
-	"net/url"
 	"os"
+	"sort"
 	"strconv"
+	"strings"
+	"time"
 
+	"github.com/Nastradamus/useless-operator/pkg/apis/useless/v1alpha1"
+	"github.com/Nastradamus/useless-operator/pkg/history"
+	"github.com/Nastradamus/useless-operator/pkg/notify"
+	"github.com/Nastradamus/useless-operator/pkg/policy"
 	prom "github.com/Nastradamus/useless-operator/pkg/prometheus"
+	"github.com/Nastradamus/useless-operator/pkg/report"
 	ukube "github.com/Nastradamus/useless-operator/pkg/ukubernetes"
 )
 
 func main() {
-	// Parse and validate flags, setup logging
-	var (
-		v                 = flag.Int("v", 1, "Verbosity level (klog).")
-		profile           = flag.Bool("profile", false, "Enable profiling on http://0.0.0.0:6060")
-		period            = flag.Int("period", 6, "Observation period in hours.")
-		promAddr          = flag.String("prom-uri", "", "Prometheus URI (e.g. http://localhost:9091).")
-		runOutsideCluster = flag.Bool("run-outside-cluster", false, "Set this flag when running "+
-			"outside of the cluster.")
-	)
-	var Usage = func() {
-		_, _ = fmt.Fprintf(flag.CommandLine.Output(), "Usage of %s:\n", os.Args[0])
-		flag.PrintDefaults()
-	}
-
-	klogFlags := flag.NewFlagSet("klog", flag.ExitOnError)
-
-	flag.Parse()
-
-	klog.InitFlags(klogFlags)
-	klog.SetOutput(os.Stdout)
-
-	verbosity := klogFlags.Lookup("v")
-	_ = verbosity.Value.Set(strconv.Itoa(*v))
-
-	klog.V(0).Infof("Verbosity level set to %v", klogFlags.Lookup("v").Value)
-
-	if *profile {
-		klog.V(0).Infof("Profiling enabled. URL: http://0.0.0.0:6060/debug/pprof/" +
-			"Usage:\n" +
-			"http://localhost:6060/debug/pprof/\n" +
-			"go tool pprof http://0.0.0.0:6060/debug/pprof/heap\n" +
-			"go tool pprof http://0.0.0.0:6060/debug/pprof/profile?seconds=30\n" +
-			"go tool pprof http://0.0.0.0:6060/debug/pprof/block\n" +
-			"wget http://0.0.0.0:6060/debug/pprof/trace?seconds=5\n" +
-			"go tool pprof http://0.0.0.0:6060/debug/pprof/mutex\n" +
-			"To view all available profiles, open http://0.0.0.0:6060/debug/pprof/ in your browser. ")
-		go func() {
-			klog.Infoln(http.ListenAndServe("0.0.0.0:6060", nil))
-		}()
-	}
-
-	// Check Prometheus endpoint's syntax
-	_, err := url.ParseRequestURI(*promAddr)
-	if err != nil {
-		Usage()
-		klog.Exit(err)
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(exitUsage)
+	}
+	name, args := os.Args[1], os.Args[2:]
+	switch name {
+	case "help", "-h", "-help", "--help":
+		usage()
+		os.Exit(exitOK)
+	}
+	if strings.HasPrefix(name, "-") {
+		// Flags without a command configured everything before commands existed
+		klog.Warning("Running without a command is deprecated, use 'remediate'")
+		name, args = "remediate", os.Args[1:]
+	}
+	for _, cmd := range commands() {
+		if cmd.name == name {
+			os.Exit(cmd.run(args))
+		}
 	}
 
-	// Get kubernetes config
-	config, err := ukube.GetConfig(*runOutsideCluster)
+	_, _ = fmt.Fprintf(os.Stderr, "Unknown command %q\n\n", name)
+	usage()
+	os.Exit(exitUsage)
+}
+
+// clusterScan holds findings of a cluster and what acting on them needs
+type clusterScan struct {
+	cluster  *cluster
+	report   report.Report
+	settings *policy.Resolver
+	defaults policy.Settings
+	marking  bool
+	// Idle workloads, by "namespace/Kind/name" too
+	workloads        []*report.IdleWorkload
+	idleWorkloadsSet map[string]bool
+	// Idle workloads can't be told from workloads whose traffic resumed without pod traffic
+	podTrafficObserved bool
+	// Longest period (hours) signals observed
+	observedWindow int
+	// Ingresses with paths without traffic during ingressWindow (hours)
+	idleIngresses []*idleIngress
+	ingressWindow int
+	// Webhooks of teams set by policies
+	teamURLs map[string]string
+}
+
+// idleIngress is an Ingress with paths without traffic, they aren't findings but developers are told via Events
+type idleIngress struct {
+	Namespace string
+	Name      string
+	// "host/path -> service:port" of idle paths
+	Paths []string
+}
+
+// scan finds useless objects in the clusters, reports them, delivers findings and acts on them according to flags
+// and policies. Findings of all clusters are delivered as one report.
+func scan(o *options, clusters []*cluster) (*report.Report, error) {
+	teamURLs, err := o.teamURLs()
 	if err != nil {
-		klog.Exit(err)
+		return nil, err
 	}
 
-	klog.V(0).Infof("Starting useless-operator...")
+	var scans []*clusterScan
+	var scanReport report.Report
+	for _, c := range clusters {
+		if c.name != "" {
+			klog.V(1).Infof("Scanning cluster %v (%v)...", c.name, c.promAddr)
+		}
+		s, err := detect(o, c)
+		if err != nil {
+			if c.name != "" {
+				return nil, fmt.Errorf("cluster %v: %v", c.name, err)
+			}
+			return nil, err
+		}
+		for i := range s.report.Findings {
+			s.report.Findings[i].Cluster = c.name
+		}
+		for team, url := range s.teamURLs {
+			teamURLs[team] = url
+		}
+		scanReport.Add(s.report.Findings...)
+		scans = append(scans, s)
+	}
+	// Reports of clusters share findings with the combined report, so history and lifecycle states show in both
+	start := 0
+	for _, s := range scans {
+		end := start + len(s.report.Findings)
+		s.report.Findings = scanReport.Findings[start:end:end]
+		start = end
+	}
 
-	// Get tested k8s client
-	kClient, err := ukube.GetKClient(config)
+	// Tell how long objects are found and what changed since the previous scan. A ConfigMap store is kept in
+	// the first cluster.
+	historyStore, err := history.New(o.historySpec, clusters[0].kClient)
 	if err != nil {
-		klog.Exit(err)
+		return nil, err
+	}
+	if historyStore != nil {
+		// History which can't be read isn't overwritten
+		scanHistory, err := historyStore.Load()
+		if err != nil {
+			klog.Warningf("Can't load scan history: %v", err)
+		} else {
+			diff := scanHistory.Record(&scanReport, time.Now())
+			klog.V(1).Infof("Scan history: %v\n", diff.String())
+			for _, key := range diff.Resolved {
+				klog.V(2).Infof("Resolved since the previous scan: %v", key)
+			}
+			if err := historyStore.Save(scanHistory); err != nil {
+				klog.Warningf("Can't save scan history: %v", err)
+			}
+		}
 	}
 
-	//ololo, err := kClient.AppsV1().Deployments("ops").List(metav1.ListOptions{})
-	//if err != nil {
-	//	log.Printf("ERROR: %v", err)
-	//}
+	for _, s := range scans {
+		s.act(o)
+	}
 
-	// podCpu, podMem, err := ukube.GetPodRequests("ops-test", "busybox1", kClient)
-	// fmt.Printf("\nCPU: %v, memory: %v\n\n", podCpu, podMem)
+	klog.V(1).Infof("Use the following commands to free resources in the cluster:\n")
+	fmt.Println()
+	scanReport.Print(os.Stdout)
+	if o.output != "" {
+		scanReport.Time = time.Now()
+		if err := scanReport.Save(o.output); err != nil {
+			klog.Warningf("Can't save the report: %v", err)
+		}
+	}
+
+	// Send each team its own summary
+	if o.webhookURL != "" || len(teamURLs) > 0 {
+		notifier, err := notify.NewNotifier(o.notifyConfig(teamURLs))
+		if err != nil {
+			return nil, err
+		}
+		notified, err := notifier.Notify(&scanReport)
+		if err != nil {
+			klog.Warningf("%v", err)
+		}
+		klog.V(1).Infof("Teams notified: %v\n", notified)
+	}
 
-	// Init a map of useless pods ("Element" key is a Pod, Pod's value is a Deployment (string))
+	return &scanReport, nil
+}
+
+// detect finds useless objects in the cluster and attributes them to owners
+func detect(o *options, c *cluster) (*clusterScan, error) {
+	kClient, dClient, ingAPI, policies := c.kClient, c.dClient, c.ingAPI, c.policies
+
+	// Flags are defaults of objects without policies
+	action := v1alpha1.ActionReport
+	if o.lifecycle {
+		action = v1alpha1.ActionLifecycle
+	} else if o.markWorkloads {
+		action = v1alpha1.ActionMark
+	}
+	defaults := policy.Settings{
+		ObservationPeriod:   time.Duration(o.period) * time.Hour,
+		JobAge:              time.Duration(o.jobAge) * 24 * time.Hour,
+		CronJobSuspendedAge: time.Duration(o.cronJobSuspendedAge) * 24 * time.Hour,
+		CronJobFailedRuns:   o.cronJobFailedRuns,
+		HPAUtilization:      o.hpaUtilization,
+		NamespaceAge:        time.Duration(o.namespaceAge) * 24 * time.Hour,
+		Action:              action,
+		Lifecycle: ukube.LifecycleConfig{
+			WarnAfter:      time.Duration(o.warnAfter) * time.Hour,
+			ScaleDownAfter: time.Duration(o.scaleDownAfter) * time.Hour,
+			DeleteAfter:    time.Duration(o.deleteAfter) * time.Hour,
+		},
+	}
+	metadata := ukube.NewMetadataCache(kClient, dClient)
+	settings := policy.NewResolver(policies, metadata, defaults)
+
+	// Detectors find objects for all policies, findings are filtered by their own policies
+	scanSettings := policy.ScanSettings(policies.Policies(), defaults)
+	scanPeriod := int(scanSettings.ObservationPeriod.Hours())
+	// Only commands acting on workloads let policies mark them
+	marking := action != v1alpha1.ActionReport
+	for _, p := range policies.Policies() {
+		if p.Spec.Lifecycle.Action == v1alpha1.ActionMark || p.Spec.Lifecycle.Action == v1alpha1.ActionLifecycle {
+			marking = true
+		}
+	}
+	marking = marking && o.act
+
+	// Gateway API CRDs are optional, routes are analyzed only if they are served
+	gwAPI, err := ukube.NewGatewayAPI(kClient, dClient)
+	if err != nil {
+		return nil, err
+	}
+
+	// Init a map of useless pods ("Element" key is a Pod, Pod's value is its workload ("Kind/name"))
 	var uselessPodsMap = map[prom.Namespace]map[prom.Element]string{}
 
-	// Init a map of unused Deployments (Element is Deployment)
-	var uselessDeploymentsMap = map[prom.Namespace]map[prom.Element]string{}
+	// Idle workloads found by all signals
+	var idleWorkloads report.WorkloadSet
+	var scanReport report.Report
 
 	//
 	// PART 1
@@ -100,11 +227,15 @@ func main() {
 	klog.V(3).Info("Querying Prometheus for unused pods...")
 	promQueryPods := `sum(rate(container_network_transmit_packets_total{container_name="POD", 
 				service="prometheus-operator-kubelet"}[1h])) by (namespace, pod_name) == 0`
-	promPodsMap, observedPeriod, err := prom.GetUnusedResources(*promAddr, *period, promQueryPods)
+	promPodsMap, observedPeriod, err := prom.GetUnusedResources(c.promClient, scanPeriod, promQueryPods)
 	if err != nil {
 		// Resource may disappear, don't panic
 		klog.Warningf("%v", err)
 	}
+	// Idle workloads can't be told from workloads whose traffic resumed without pod traffic
+	podTrafficObserved := err == nil
+	// Longest period (hours) signals observed
+	observedWindow := observedPeriod
 
 	// Estimate resources of unused pods during given observation period
 	klog.V(3).Info("Estimating resources of unused pods during given observation period (querying API)...")
@@ -116,11 +247,12 @@ func main() {
 		// Pods
 		for pod := range promPodsMap[namespace] {
 			UselessPodsCnt++
-			var podCpu, podMem, err = ukube.GetPodRequests(kClient, string(namespace), string(pod))
+			podObj, err := kClient.CoreV1().Pods(string(namespace)).Get(string(pod), metav1.GetOptions{})
 			if err != nil {
 				klog.V(4).Infof("%v (resource may disappear)", err)
 				continue
 			}
+			podCpu, podMem := ukube.PodRequests(podObj)
 
 			allPodsCpu += podCpu
 			allPodsMem += podMem
@@ -128,31 +260,28 @@ func main() {
 			klog.V(4).Infof("Namespace: %v, POD: %v, Reqests: mCPU: %v, memory (bytes): %v\n", namespace,
 				pod, podCpu, podMem)
 
-			// Get pod's Deployment
-			deployments, err := ukube.GetPodDeployment(kClient, string(namespace), string(pod))
+			// Get pod's workload
+			workload, err := ukube.GetPodWorkload(kClient, podObj)
 			if err != nil {
 				klog.V(4).Infof("%v (resource may disappear)", err)
 				continue
 			}
 
-			if len(deployments) > 1 {
-				klog.V(0).Infof("ERROR: more than 1 deployments of one pod: %v/%v", string(namespace), string(pod))
-				os.Exit(42)
-			}
-
-			// Non-deployment pod. TODO: detect pods without replication controller
-			if len(deployments) == 0 {
+			// Bare pod. TODO: detect pods without replication controller
+			if workload.Kind == "" {
 				continue
 			}
 
-			prom.MapAdd(uselessPodsMap, namespace, pod, deployments[0])
-			klog.V(4).Infof("\n\npod: '%v/%v', Deployment:\n %v\n\n", string(namespace), string(pod), deployments[0])
+			prom.MapAdd(uselessPodsMap, namespace, pod, workload.Kind+"/"+workload.Name)
+			idleWorkloads.Add(string(namespace), workload.Kind, workload.Name, report.SignalPodTraffic,
+				podObj.Name, podCpu, podMem)
+			klog.V(4).Infof("pod: '%v/%v', workload: %v/%v", namespace, pod, workload.Kind, workload.Name)
 		}
 		ObservedNamespacesCnt++
 	}
 
 	klog.V(1).Infof("Requested period: %v hours, Observed period: %v hours, "+
-		"Unused PODs count (no traffic): %v pods in %v namespaces\n", *period, observedPeriod, UselessPodsCnt,
+		"Unused PODs count (no traffic): %v pods in %v namespaces\n", scanPeriod, observedPeriod, UselessPodsCnt,
 		len(promPodsMap))
 	klog.V(1).Infof("Reqests of unused pods: CPU: %v, memory (MB): %v\n", float64(allPodsCpu)/1000, allPodsMem/1024/1024)
 
@@ -163,16 +292,38 @@ func main() {
 	// Get unused ingresses
 	klog.V(3).Info("Getting unused ingresses...")
 
-	IngressMap := prom.IngressMap{} // TODO: move outside infinite loop
-	promQueryIngresses := `sum(rate(nginx_ingress_controller_request_size_count{exported_namespace!=""
-,ingress!="",host!="",path!=""}[1h])) by (exported_namespace, ingress, host, path) == 0`
+	var ingressProviders []*prom.IngressProvider
+	if o.ingressProvider == "auto" {
+		// Prometheus may be back by the next scan
+		if ingressProviders, err = prom.DetectIngressProviders(c.promClient); err != nil {
+			klog.Warningf("%v", err)
+		}
+	} else if ingressProviders, err = prom.GetIngressProviders(splitList(o.ingressProvider)); err != nil {
+		return nil, err
+	}
 
-	IngObservedPeriod, err := IngressMap.GetUnusedIngresses(*promAddr, *period, promQueryIngresses)
-	if err != nil {
-		klog.V(4).Infof("%v (resource may disappear)", err)
+	IngObservedPeriod := 0
+	var queriedProviders []*prom.IngressProvider
+	var providerMaps []prom.IngressMap
+	for _, provider := range ingressProviders {
+		providerMap := prom.IngressMap{}
+		providerObservedPeriod, err := providerMap.GetUnusedIngresses(c.promClient, scanPeriod, provider)
+		if err != nil {
+			klog.V(4).Infof("%v (resource may disappear)", err)
+			continue
+		}
+		queriedProviders = append(queriedProviders, provider)
+		providerMaps = append(providerMaps, providerMap)
+		if providerObservedPeriod > IngObservedPeriod {
+			IngObservedPeriod = providerObservedPeriod
+		}
 	}
+	IngressMap := prom.CombineIngressMaps(queriedProviders, providerMaps) // TODO: move outside infinite loop
 
 	klog.V(1).Infof("'Unused Ingresses' observed period: %v\n", IngObservedPeriod)
+	if IngObservedPeriod > observedWindow {
+		observedWindow = IngObservedPeriod
+	}
 
 	// Get backends of unused ingresses, estimate their pods requested resources
 
@@ -181,75 +332,522 @@ func main() {
 	allPodsCpu = 0 // milli
 	allPodsMem = 0 // bytes
 
-	for ns, ingMap := range IngressMap.M {
-		for ing, hostMap := range ingMap {
-			for host, pathMap := range hostMap {
-				for path := range pathMap {
-					back, err := ukube.GetIngressBackend(kClient, string(ns), string(ing), string(host), string(path))
-					if err != nil {
-						klog.Warningf("%v", err)
-					}
-					// Add Ingress backend into shared IngressMap
-					IngressMap.M[ns][ing][host][path] = prom.IngressBackend(back)
-					klog.V(4).Infof("ns: %v, ing: %v, host: %v, path: %v, back: %v", ns, ing, host, path, back)
-
-					// Get services behind backends
-					selector, err := ukube.GetSvcSelectorByIngressBackend(kClient, string(ns), prom.IngressBackend(back).ServiceName)
-					if err != nil {
-						klog.Warningf("%v", err)
-					}
-					klog.V(4).Infof("Selector: %v", selector)
-
-					pods, err := ukube.GetPodsBySelector(kClient, string(ns), selector)
-					if err != nil {
-						klog.Warningf("%v", err)
-					}
-
-					for _, podName := range pods.Items {
-						klog.V(4).Infof("Pod: %v", podName.Name)
-						podCpu, podMem, err := ukube.GetPodRequests(kClient, string(ns), podName.Name)
-						if err != nil {
-							klog.V(4).Infof("%v (resource may disappear)", err)
-							continue
-						}
-
-						UselessPodsCnt += 1
-						allPodsCpu += podCpu
-						allPodsMem += podMem
-
-					}
+	// Namespaces are needed to split `<namespace>-<service>` names of some providers
+	var namespaces []string
+	nsList, err := kClient.CoreV1().Namespaces().List(metav1.ListOptions{})
+	if err != nil {
+		klog.Warningf("%v", err)
+	} else {
+		for _, ns := range nsList.Items {
+			namespaces = append(namespaces, ns.Name)
+		}
+	}
+
+	// Idle Gateway API routes (printed with their details)
+	var idleRoutes []*ukube.Route
+	// Ingresses with idle paths by "namespace/name"
+	idleIngresses := map[string]*idleIngress{}
+
+	for key := range IngressMap.M {
+		var services []ukube.ServiceRef
+		signal := report.SignalIngressTraffic
+		ns := string(key.Namespace)
+
+		switch {
+		case key.Route != "":
+			route, err := gwAPI.GetRoute(key.RouteKind, ns, key.Route)
+			if err != nil {
+				klog.V(4).Infof("%v (resource may disappear)", err)
+				continue
+			}
+			rule := -1
+			if key.RouteRule != "" {
+				rule, err = strconv.Atoi(key.RouteRule)
+				if err != nil {
+					klog.V(4).Infof("%v/%v: bad rule index: %v", ns, key.Route, err)
+					continue
 				}
 			}
+			idleRoutes = append(idleRoutes, route)
+			services = route.BackendServices(rule)
+			signal = report.SignalRouteTraffic
+		case key.Service != "" && ns == "":
+			namespace, service, ok := prom.SplitNamespacedName(key.Service, namespaces)
+			if !ok {
+				klog.V(4).Infof("Can't find namespace of service %v", key.Service)
+				continue
+			}
+			services = []ukube.ServiceRef{{Namespace: namespace, Name: service}}
+		case key.Service != "":
+			services = []ukube.ServiceRef{{Namespace: ns, Name: key.Service}}
+		default:
+			ingress, err := ingAPI.Get(ns, string(key.Ingress))
+			if err != nil {
+				klog.V(4).Infof("%v (resource may disappear)", err)
+				continue
+			}
+			if !ingAPI.InScope(ingress) {
+				continue
+			}
+
+			back, ok := ingress.IdleBackend(string(key.Host), string(key.Path))
+			if !ok {
+				klog.V(4).Infof("%v/%v host: %v, path: %v: no matching backend", ns, key.Ingress, key.Host, key.Path)
+				continue
+			}
+			// Add the most specific Ingress backend into shared IngressMap
+			IngressMap.M[key] = prom.IngressBackend{ServiceName: back.ServiceName, ServicePort: back.ServicePort}
+
+			idle, ok := idleIngresses[ns+"/"+ingress.Name]
+			if !ok {
+				idle = &idleIngress{Namespace: ns, Name: ingress.Name}
+				idleIngresses[ns+"/"+ingress.Name] = idle
+			}
+			backend := "resource backend"
+			if back.ServiceName != "" {
+				backend = back.ServiceName + ":" + back.ServicePort.String()
+			}
+			idle.Paths = append(idle.Paths, fmt.Sprintf("%v%v -> %v", key.Host, key.Path, backend))
+
+			// Resource backends have no pods
+			if back.ServiceName != "" {
+				services = append(services, ukube.ServiceRef{Namespace: ns, Name: back.ServiceName})
+			}
+		}
+		klog.V(4).Infof("key: %+v, services: %v", key, services)
+
+		for _, svc := range services {
+			// Get pods behind backend's service
+			pods, err := ukube.GetServicePods(kClient, svc.Namespace, svc.Name)
+			if err != nil {
+				// Broken backends are reported by the dangling ingresses detector
+				klog.V(4).Infof("%v (resource may disappear)", err)
+				continue
+			}
+
+			for i := range pods {
+				pod := &pods[i]
+				klog.V(4).Infof("Pod: %v", pod.Name)
+				podCpu, podMem := ukube.PodRequests(pod)
+
+				UselessPodsCnt += 1
+				allPodsCpu += podCpu
+				allPodsMem += podMem
+
+				workload, err := ukube.GetPodWorkload(kClient, pod)
+				if err != nil {
+					klog.V(4).Infof("%v (resource may disappear)", err)
+					continue
+				}
+				if workload.Kind == "" {
+					continue
+				}
+				idleWorkloads.Add(svc.Namespace, workload.Kind, workload.Name, signal, pod.Name, podCpu, podMem)
+			}
 		}
 	}
 
 	klog.V(1).Infof("\nIngresses: Unused PODs count from Ingresses (no traffic): %v \n", UselessPodsCnt)
 	klog.V(1).Infof("Ingresses Reqests: CPU: %v, memory (MB): %v\n", float64(allPodsCpu)/1000, allPodsMem/1024/1024)
 
-	// Print command for cleanup useless Deployments
+	var idleIngressList []*idleIngress
+	for _, idle := range idleIngresses {
+		sort.Strings(idle.Paths)
+		idleIngressList = append(idleIngressList, idle)
+	}
+
+	klog.V(1).Infof("Idle Gateway API routes: %v\n", len(idleRoutes))
+	for _, route := range idleRoutes {
+		scanReport.Add(idleRouteFinding(route))
+	}
+
+	//
+	// PART 3
+	//
 
-	// Fill uselessDeploymentsMap
-	for namespace, podMap := range uselessPodsMap {
-		for _, deployment := range podMap {
-			prom.MapAdd(uselessDeploymentsMap, namespace, prom.Element(deployment), "")
+	// Get workloads without application requests according to service mesh metrics. Sidecars' own
+	// telemetry keeps packet counters above zero, so PART 1 can't see them.
+	klog.V(3).Info("Getting workloads without service mesh requests...")
+
+	var meshProviders []*prom.MeshProvider
+	switch o.meshProvider {
+	case "auto":
+		if meshProviders, err = prom.DetectMeshProviders(c.promClient); err != nil {
+			klog.Warningf("%v", err)
+		}
+	case "none":
+	default:
+		if meshProviders, err = prom.GetMeshProviders(splitList(o.meshProvider)); err != nil {
+			return nil, err
 		}
 	}
 
-	klog.V(1).Infof("Use the following commands to free resources in the cluster:\n")
-	fmt.Println()
-	for namespace, depMap := range uselessDeploymentsMap {
-		for deployment := range depMap {
-			fmt.Printf("kubectl -n %v scale deployment %v --replicas=0\n", namespace, deployment)
+	meshWorkloadsCnt := 0
+	for _, provider := range meshProviders {
+		meshWorkloads, meshObservedPeriod, err := prom.GetUnusedMeshWorkloads(c.promClient, scanPeriod, provider)
+		if err != nil {
+			klog.Warningf("%v", err)
+			continue
+		}
+		klog.V(1).Infof("'Unused mesh workloads' (%v) observed period: %v\n", provider.Name, meshObservedPeriod)
+		if meshObservedPeriod > observedWindow {
+			observedWindow = meshObservedPeriod
+		}
+
+		for mw := range meshWorkloads {
+			workload, pods, err := ukube.GetWorkloadPods(kClient, mw.Namespace, mw.Kind, mw.Name)
+			if err != nil {
+				klog.V(4).Infof("%v (resource may disappear)", err)
+				continue
+			}
+			meshWorkloadsCnt++
+			for i := range pods {
+				podCpu, podMem := ukube.PodRequests(&pods[i])
+				idleWorkloads.Add(mw.Namespace, workload.Kind, workload.Name, report.SignalMeshTraffic,
+					pods[i].Name, podCpu, podMem)
+			}
 		}
 	}
+	klog.V(1).Infof("Workloads without service mesh requests: %v\n", meshWorkloadsCnt)
+
+	//
+	// PART 4
+	//
+
+	// Get ingresses which backends can't serve traffic at all
+	klog.V(3).Info("Getting dangling ingresses...")
+	danglingIngresses, err := ingAPI.GetDanglingIngresses("")
+	if err != nil {
+		klog.Warningf("%v", err)
+	}
 
-	// Don't exit if we want profiling (for now)
-	if *profile {
-		fmt.Print("Program stopped. Type something to exit: ")
-		input := bufio.NewScanner(os.Stdin)
-		input.Scan()
-		fmt.Println(input.Text())
+	klog.V(1).Infof("Dangling ingresses (missing Service, service port or endpoints): %v\n", len(danglingIngresses))
+	for _, d := range danglingIngresses {
+		scanReport.Add(danglingIngressFinding(d))
 	}
+
+	//
+	// PART 5
+	//
+
+	// Get volumes which keep storage for nothing
+	klog.V(3).Info("Getting unused volumes...")
+
+	unchangedClaims := map[string]bool{}
+	unchangedClaimsMap, volumesObservedPeriod, err := prom.GetUnusedVolumeClaims(c.promClient, scanPeriod)
+	if err != nil {
+		klog.Warningf("%v", err)
+	}
+	for namespace, claims := range unchangedClaimsMap {
+		for claim := range claims {
+			unchangedClaims[string(namespace)+"/"+string(claim)] = true
+		}
+	}
+	klog.V(1).Infof("'Unused volumes' observed period: %v\n", volumesObservedPeriod)
+
+	// Pods of idle workloads found by all signals
+	idlePods := map[string]bool{}
+	for _, w := range idleWorkloads.List() {
+		for _, pod := range w.Pods {
+			idlePods[w.Namespace+"/"+pod] = true
+		}
+	}
+
+	unusedVolumes, err := ukube.GetUnusedVolumes(kClient, idlePods, unchangedClaims)
+	if err != nil {
+		klog.Warningf("%v", err)
+	}
+
+	var unusedStorage int64 // bytes
+	for _, v := range unusedVolumes {
+		unusedStorage += v.Capacity
+		scanReport.Add(unusedVolumeFinding(v))
+	}
+	klog.V(1).Infof("Unused volumes: %v, capacity (GB): %.2f\n", len(unusedVolumes),
+		float64(unusedStorage)/1024/1024/1024)
+
+	//
+	// PART 6
+	//
+
+	// Get ConfigMaps and Secrets nothing refers to
+	klog.V(3).Info("Getting unreferenced ConfigMaps and Secrets...")
+	unreferencedConfigs, err := ukube.GetUnreferencedConfigs(kClient, dClient, ingAPI, "")
+	if err != nil {
+		klog.Warningf("%v", err)
+	}
+
+	klog.V(1).Infof("Unreferenced ConfigMaps and Secrets: %v\n", len(unreferencedConfigs))
+	for _, obj := range unreferencedConfigs {
+		scanReport.Add(unreferencedConfigFinding(obj))
+	}
+
+	//
+	// PART 7
+	//
+
+	// Get finished Jobs and suspended or always failing CronJobs
+	klog.V(3).Info("Getting stale Jobs and CronJobs...")
+	staleJobs, err := ukube.GetStaleJobs(kClient, dClient, "", scanSettings.JobAge,
+		scanSettings.CronJobSuspendedAge, scanSettings.CronJobFailedRuns)
+	if err != nil {
+		klog.Warningf("%v", err)
+	}
+	staleJobs = filterStaleJobs(staleJobs, settings)
+
+	klog.V(1).Infof("Stale Jobs and CronJobs: %v\n", len(staleJobs))
+	for _, j := range staleJobs {
+		klog.V(2).Infof("%v %v/%v: %v, age (days): %v, pods: %v, requests: CPU: %v, memory (MB): %v", j.Kind,
+			j.Namespace, j.Name, j.Reason, int(j.Age.Hours()/24), j.Pods, float64(j.CPU)/1000, j.Memory/1024/1024)
+	}
+	for _, j := range staleJobs {
+		scanReport.Add(staleJobFinding(j))
+	}
+
+	//
+	// PART 8
+	//
+
+	// Get HorizontalPodAutoscalers: those scaling idle workloads must be removed before scaling to zero,
+	// those pinned at minReplicas with idle targets reserve more replicas than needed
+	klog.V(3).Info("Getting HorizontalPodAutoscalers...")
+	hpas, err := ukube.ListHPAs(kClient, "")
+	if err != nil {
+		klog.Warningf("%v", err)
+	}
+	hpasByTarget := ukube.HPAsByTarget(hpas)
+
+	for _, w := range idleWorkloads.List() {
+		if hpa, ok := hpasByTarget[w.Namespace+"/"+w.Kind+"/"+w.Name]; ok {
+			w.HPA = hpa.Name
+			klog.V(2).Infof("Idle workload %v/%v/%v is scaled by HPA %v", w.Namespace, w.Kind, w.Name, hpa.Name)
+		}
+	}
+
+	pinnedHPAsMap, hpaObservedPeriod, err := prom.GetPinnedHPAs(c.promClient, scanPeriod,
+		scanSettings.HPAUtilization)
+	if err != nil {
+		klog.Warningf("%v", err)
+	}
+	klog.V(1).Infof("'Pinned HPAs' observed period: %v\n", hpaObservedPeriod)
+
+	pinnedHPAs := filterPinnedHPAs(hpas, pinnedHPAsMap, settings)
+
+	klog.V(1).Infof("HPAs pinned at minReplicas with CPU utilization below %v%%: %v\n", scanSettings.HPAUtilization,
+		len(pinnedHPAs))
+	for _, hpa := range pinnedHPAs {
+		scanReport.Add(pinnedHPAFinding(hpa, pinnedHPAsMap[prom.Namespace(hpa.Namespace)][prom.Element(hpa.Name)]))
+	}
+
+	//
+	// PART 9
+	//
+
+	// Get namespaces which can be reclaimed as a whole
+	klog.V(3).Info("Getting abandoned namespaces...")
+	idleWorkloadsSet := map[string]bool{}
+	for _, w := range idleWorkloads.List() {
+		idleWorkloadsSet[w.Namespace+"/"+w.Kind+"/"+w.Name] = true
+	}
+
+	abandonedNamespaces, err := ukube.GetAbandonedNamespaces(kClient, dClient, idleWorkloadsSet,
+		scanSettings.NamespaceAge, splitList(o.ownerKeys))
+	if err != nil {
+		klog.Warningf("%v", err)
+	}
+	abandonedNamespaces = filterAbandonedNamespaces(abandonedNamespaces, settings)
+
+	klog.V(1).Infof("Abandoned namespaces: %v\n", len(abandonedNamespaces))
+	for _, ns := range abandonedNamespaces {
+		scanReport.Add(abandonedNamespaceFinding(ns))
+	}
+
+	// Print command for cleanup useless workloads
+	workloads := idleWorkloads.List()
+	klog.V(1).Infof("Idle workloads: %v\n", len(workloads))
+	for _, w := range workloads {
+		klog.V(2).Infof("%v/%v/%v: signals: %v, pods: %v, requests: CPU: %v, memory (MB): %v", w.Namespace,
+			w.Kind, w.Name, w.SignalsString(), len(w.Pods), float64(w.CPU)/1000, w.Memory/1024/1024)
+	}
+
+	for _, w := range workloads {
+		scanReport.Add(w.Finding())
+	}
+
+	// Drop findings of detectors disabled by policies
+	var findings []report.Finding
+	for _, f := range scanReport.Findings {
+		s := settings.Settings(f.Namespace, f.Kind, f.Name)
+		if !s.Enabled(string(f.Category)) {
+			klog.V(3).Infof("%v is disabled by %v", f.String(), s.Policy)
+			continue
+		}
+		findings = append(findings, f)
+	}
+	scanReport.Findings = findings
+
+	teamURLs := map[string]string{}
+
+	// Attribute findings to teams by policies, owner annotations and labels of objects or their namespaces
+	owners := ukube.NewOwnerResolver(metadata, splitList(o.ownerKeys))
+	for i := range scanReport.Findings {
+		f := &scanReport.Findings[i]
+		if s := settings.Settings(f.Namespace, f.Kind, f.Name); s.Owner != "" {
+			f.Owner = s.Owner
+			if s.Webhook != "" {
+				teamURLs[s.Owner] = s.Webhook
+			}
+			continue
+		}
+		f.Owner = owners.Resolve(f.Namespace, f.Kind, f.Name)
+	}
+	for _, team := range scanReport.ByOwner() {
+		klog.V(1).Infof("%v\n", team.Summary())
+	}
+
+	return &clusterScan{
+		cluster:            c,
+		report:             scanReport,
+		settings:           settings,
+		defaults:           defaults,
+		marking:            marking,
+		workloads:          workloads,
+		idleWorkloadsSet:   idleWorkloadsSet,
+		podTrafficObserved: podTrafficObserved,
+		observedWindow:     observedWindow,
+		idleIngresses:      idleIngressList,
+		ingressWindow:      IngObservedPeriod,
+		teamURLs:           teamURLs,
+	}, nil
+}
+
+// act records Events, marks idle workloads, moves them through the lifecycle and writes reports of the cluster
+func (cs *clusterScan) act(o *options) {
+	kClient, dClient, scanReport, settings := cs.cluster.kClient, cs.cluster.dClient, &cs.report, cs.settings
+
+	// Tell developers looking at their objects why they are flagged
+	if o.recordEvents {
+		host, _ := os.Hostname()
+		recorder := ukube.NewEventRecorder(kClient, dClient, host)
+		defer recorder.Shutdown()
+		recorded := 0
+		for i := range scanReport.Findings {
+			ok, err := recordFindingEvent(recorder, &scanReport.Findings[i], cs.observedWindow)
+			if err != nil {
+				klog.V(4).Infof("%v (resource may disappear)", err)
+				continue
+			}
+			if ok {
+				recorded++
+			}
+		}
+		for _, idle := range cs.idleIngresses {
+			s := settings.Settings(idle.Namespace, "Ingress", idle.Name)
+			if !s.Enabled(string(report.CategoryIdleWorkload)) {
+				continue
+			}
+			if err := recordIdleIngressEvent(recorder, idle, cs.ingressWindow); err != nil {
+				klog.V(4).Infof("%v (resource may disappear)", err)
+				continue
+			}
+			recorded++
+		}
+		klog.V(1).Infof("Events recorded: %v\n", recorded)
+	}
+
+	// Let other tooling select idle workloads and move them through the lifecycle
+	if cs.marking {
+		marker := ukube.NewMarker(kClient, dClient)
+		host, _ := os.Hostname()
+		recorder := ukube.NewEventRecorder(kClient, dClient, host)
+		defer recorder.Shutdown()
+		lc := ukube.NewLifecycle(kClient, dClient, marker, recorder, cs.defaults.Lifecycle)
+
+		// Idle workloads' findings by "namespace/Kind/name"
+		workloadFindings := map[string]*report.Finding{}
+		for i := range scanReport.Findings {
+			f := &scanReport.Findings[i]
+			if f.Category == report.CategoryIdleWorkload {
+				workloadFindings[f.Namespace+"/"+f.Kind+"/"+f.Name] = f
+			}
+		}
+
+		scan := time.Now()
+		for _, w := range cs.workloads {
+			f, ok := workloadFindings[w.Namespace+"/"+w.Kind+"/"+w.Name]
+			s := settings.Settings(w.Namespace, w.Kind, w.Name)
+			if !ok || s.Action == v1alpha1.ActionReport {
+				continue
+			}
+			marks, err := marker.Mark(w.Namespace, w.Kind, w.Name, "no traffic: "+w.SignalsString(), scan)
+			if err != nil {
+				klog.V(4).Infof("%v (resource may disappear)", err)
+				continue
+			}
+			f.State = marks.State
+			if s.Action == v1alpha1.ActionLifecycle {
+				f.State, err = lc.WithConfig(s.Lifecycle).Advance(ukube.MarkedWorkload{Namespace: w.Namespace,
+					Kind: w.Kind, Name: w.Name, Marks: marks}, w.HPA != "", scan)
+				if err != nil {
+					klog.Warningf("%v", err)
+				}
+			}
+		}
+
+		// Scaled down workloads have no pods, so they aren't found idle anymore
+		scaledDown, err := marker.List(ukube.StateScaledDown)
+		if err != nil {
+			klog.Warningf("%v", err)
+		}
+		for _, w := range scaledDown {
+			s := settings.Settings(w.Namespace, w.Kind, w.Name)
+			if s.Action != v1alpha1.ActionLifecycle {
+				continue
+			}
+			state, err := lc.WithConfig(s.Lifecycle).Advance(w, false, scan)
+			if err != nil {
+				klog.Warningf("%v", err)
+				continue
+			}
+			klog.V(2).Infof("%v/%v/%v: %v since %v", w.Namespace, w.Kind, w.Name, state,
+				w.Marks.StateSince.Format(time.RFC3339))
+		}
+		if cs.podTrafficObserved {
+			unmarked, err := marker.UnmarkResumed(cs.idleWorkloadsSet)
+			if err != nil {
+				klog.Warningf("%v", err)
+			}
+			klog.V(1).Infof("Workloads with resumed traffic unmarked: %v\n", len(unmarked))
+			for _, key := range unmarked {
+				klog.V(2).Infof("Unmarked %v", key)
+			}
+		} else {
+			klog.Warning("Pod traffic wasn't observed, keeping marks of workloads not found idle")
+		}
+	}
+
+	// Let teams and other controllers read findings from the API
+	if o.writeReports {
+		written, err := report.NewPublisher(dClient).Publish(scanReport,
+			time.Duration(cs.observedWindow)*time.Hour, time.Now())
+		if err != nil {
+			klog.Warningf("%v", err)
+		}
+		klog.V(1).Infof("Namespace reports written: %v\n", written)
+	}
+}
+
+// splitList splits comma-separated flag value, skipping empty items
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item != "" {
+			items = append(items, item)
+		}
+	}
+
+	return items
 }
 
 // TODO: